@@ -0,0 +1,307 @@
+// Package cli implements the non-interactive subcommands of the wallet, for use from scripts, cron and CI.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/client"
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/config"
+	"github.com/spacemeshos/CLIWallet/output"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Exit codes of the subcommands
+const (
+	ExitOK         = 0 // success
+	ExitError      = 1 // any other error
+	ExitUsage      = 2 // invalid command line
+	ExitWallet     = 3 // the wallet can't be opened or the password is wrong
+	ExitConnection = 4 // the API server can't be reached
+	ExitRejected   = 5 // the transaction was not accepted, or was rejected by the network
+	ExitPending    = 6 // the transaction is not processed yet, or the node is not synced
+	ExitNotFound   = 7 // the transaction is unknown
+)
+
+// DefaultPasswordEnv is the environment variable the wallet password is read from by default
+const DefaultPasswordEnv = "CLI_WALLET_PASSWORD"
+
+// DefaultApprovalPasswordEnv is the environment variable the second password of a spending policy is read from by default
+const DefaultApprovalPasswordEnv = "CLI_WALLET_APPROVAL_PASSWORD"
+
+// exitError is an error which ends a subcommand with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func exitErrorf(code int, format string, args ...interface{}) error {
+	return &exitError{code, fmt.Errorf(format, args...)}
+}
+
+// exitCode returns the exit code for the error a subcommand returned
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return ExitConnection
+	}
+	return ExitError
+}
+
+// subcommand is a non-interactive command
+type subcommand struct {
+	name        string
+	description string
+	run         func(o *options, args []string, out io.Writer) error
+}
+
+var subcommands = []subcommand{
+	{"accounts", "List the accounts of a wallet", runAccounts},
+	{"balance", "Display the balance of an account", runBalance},
+	{"send", "Send coins from a wallet account", runSend},
+	{"tx-status", "Display the state of a transaction", runTxStatus},
+	{"node-status", "Display the sync status of the node", runNodeStatus},
+	{"known-servers", "List the servers which have a pinned public key", runKnownServers},
+	{"trust-server", "Pin the public key the server set with -server presents", runTrustServer},
+	{"forget-server", "Remove the pinned public key of the server set with -server", runForgetServer},
+}
+
+// IsCommand returns true iff name is a subcommand
+func IsCommand(name string) bool {
+	if name == "help" {
+		return true
+	}
+	for _, c := range subcommands {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the subcommand named by args[0] with the rest of args as its flags and returns the exit code.
+// Results are written to stdout and errors to stderr.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" {
+		usage(stdout)
+		return ExitOK
+	}
+	for _, c := range subcommands {
+		if c.name != args[0] {
+			continue
+		}
+		o := &options{}
+		fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		o.register(fs)
+		err := c.run(o, args[1:], stdout)
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		if err != nil && o.json {
+			(&output.JSON{W: stdout}).RenderError(err)
+		} else if err != nil {
+			fmt.Fprintln(stderr, "error:", err)
+		}
+		return exitCode(err)
+	}
+	fmt.Fprintln(stderr, "unknown command", args[0])
+	usage(stderr)
+	return ExitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cli_wallet <command> [flags]")
+	fmt.Fprintln(w, "commands:")
+	for _, c := range subcommands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.description)
+	}
+	fmt.Fprintln(w, "Use cli_wallet <command> -h for the flags of a command")
+}
+
+// options are the flags shared by all the subcommands
+type options struct {
+	fs           *flag.FlagSet
+	server       string
+	secure       bool
+	connection   client.ConnectionOptions // set by parse, from the profile and the flags
+	timeout      time.Duration
+	walletDir    string
+	wallet       string
+	passwordEnv  string
+	passwordFile string
+	passwordFd   int
+	json         bool
+	configFile   string
+	profileName  string
+	profile      *config.Profile // set by parse, from the config file and the flags
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	o.fs = fs
+	fs.StringVar(&o.server, "server", client.DefaultGRPCServer, "The Spacemesh api grpc server host and port, or a comma separated list of servers to fail over to")
+	fs.BoolVar(&o.secure, "secure", client.DefaultSecureConnection, "Connect securely to the server")
+	fs.DurationVar(&o.timeout, "timeout", client.DefaultCallTimeout, "Time given to the API server to answer a call. 0 means no deadline")
+	fs.StringVar(&o.walletDir, "wallet_directory", ".", "Directory of the wallet file")
+	fs.StringVar(&o.wallet, "wallet", "", "Wallet file")
+	fs.StringVar(&o.passwordEnv, "password-env", DefaultPasswordEnv, "Environment variable holding the wallet password")
+	fs.StringVar(&o.passwordFile, "password-file", "", "File holding the wallet password")
+	fs.IntVar(&o.passwordFd, "password-fd", -1, "File descriptor to read the wallet password from")
+	config.RegisterConnectionFlags(fs)
+	fs.StringVar(&o.configFile, "config", "", "Config file with the network profiles. Defaults to "+config.DefaultFile())
+	fs.StringVar(&o.profileName, "profile", "", "Network profile of the config file. -server, -secure, -wallet_directory and -wallet override its settings")
+	fs.BoolVar(&o.json, "json", false, "Write the result, or the error, as json to the standard output")
+}
+
+// render writes the result of a subcommand as text or as json
+func (o *options) render(out io.Writer, v output.View) error {
+	if o.json {
+		return (&output.JSON{W: out}).Render(v)
+	}
+	return (&output.Text{W: out}).Render(v)
+}
+
+// parse parses the flags of a subcommand, which takes no positional arguments
+func (o *options) parse(args []string) error {
+	if err := o.fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &exitError{ExitUsage, err}
+	}
+	if o.fs.NArg() > 0 {
+		return exitErrorf(ExitUsage, "unexpected argument %s", o.fs.Arg(0))
+	}
+	return o.loadProfile()
+}
+
+// isSet returns true iff the flag called name is set on the command line
+func (o *options) isSet(name string) bool {
+	set := false
+	o.fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// loadProfile sets the connection and wallet settings from the profile and the flags
+func (o *options) loadProfile() error {
+	file := o.configFile
+	if file == "" {
+		file = config.DefaultFile()
+	}
+	cfg, err := config.Load(file, o.configFile != "")
+	if err != nil {
+		return &exitError{ExitUsage, err}
+	}
+	if o.profile, err = cfg.Profile(o.profileName); err != nil {
+		return &exitError{ExitUsage, err}
+	}
+	if err := o.profile.Override(o.fs); err != nil {
+		return &exitError{ExitUsage, err}
+	}
+	if err := common.SetAmountUnits(o.profile.Units); err != nil {
+		return &exitError{ExitUsage, err}
+	}
+	o.connection, o.timeout = o.profile.Connection(), o.profile.Timeout
+	if o.profile.WalletDir != "" {
+		o.walletDir = o.profile.WalletDir
+	}
+	o.wallet = o.profile.Wallet
+	return nil
+}
+
+// password reads the wallet password from the file descriptor, the file or the environment variable, in that order
+func (o *options) password() (string, error) {
+	var b []byte
+	var err error
+	switch {
+	case o.passwordFd >= 0:
+		f := os.NewFile(uintptr(o.passwordFd), "password")
+		if f == nil {
+			return "", exitErrorf(ExitUsage, "invalid password file descriptor %d", o.passwordFd)
+		}
+		b, err = ioutil.ReadAll(f)
+		f.Close()
+	case o.passwordFile != "":
+		b, err = ioutil.ReadFile(o.passwordFile)
+	default:
+		p, ok := os.LookupEnv(o.passwordEnv)
+		if !ok {
+			return "", exitErrorf(ExitWallet, "no wallet password. Set %s, or use -password-file or -password-fd", o.passwordEnv)
+		}
+		b = []byte(p)
+	}
+	if err != nil {
+		return "", &exitError{ExitWallet, fmt.Errorf("failed to read the wallet password: %v", err)}
+	}
+	// password files usually end with a new line
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// approvalPassword reads the second password of a spending policy from file, or else from the environment variable env
+func approvalPassword(file, env string) (string, error) {
+	if file == "" {
+		p, ok := os.LookupEnv(env)
+		if !ok {
+			return "", exitErrorf(ExitUsage, "the spending policy requires the second password for this transfer. Set %s or use -approval-password-file", env)
+		}
+		return p, nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", exitErrorf(ExitUsage, "failed to read the second password: %v", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// connect connects to the API server without opening a wallet
+func (o *options) connect() (*client.WalletBackend, error) {
+	be, err := client.OpenConnection(o.connection, o.walletDir)
+	if err != nil {
+		return nil, &exitError{ExitConnection, err}
+	}
+	be.SetTimeout(o.timeout)
+	return be, nil
+}
+
+// openWallet opens the wallet set by -wallet and connects to the API server
+func (o *options) openWallet() (*client.WalletBackend, error) {
+	if o.wallet == "" {
+		return nil, exitErrorf(ExitUsage, "-wallet is required")
+	}
+	password, err := o.password()
+	if err != nil {
+		return nil, err
+	}
+	path := o.wallet
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(o.walletDir, path)
+	}
+	be, err := client.OpenWalletBackendWithPassword(path, password, o.connection)
+	if err != nil {
+		return nil, &exitError{ExitWallet, fmt.Errorf("failed to open wallet %s: %v", path, err)}
+	}
+	be.SetTimeout(o.timeout)
+	return be, nil
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRunUsage(t *testing.T) {
+	var out, errOut bytes.Buffer
+	assert.Equal(t, ExitOK, Run([]string{"help"}, &out, &errOut))
+	assert.Contains(t, out.String(), "node-status")
+
+	assert.Equal(t, ExitUsage, Run([]string{"transfer"}, &out, &errOut))
+	assert.Equal(t, ExitUsage, Run([]string{"send", "-to", "0x1234"}, &out, &errOut))
+	assert.Equal(t, ExitUsage, Run([]string{"send", "-bogus"}, &out, &errOut))
+	assert.Equal(t, ExitUsage, Run([]string{"tx-status"}, &out, &errOut))
+	assert.Equal(t, ExitUsage, Run([]string{"accounts", "extra"}, &out, &errOut))
+	assert.Equal(t, ExitOK, Run([]string{"balance", "-h"}, &out, &errOut))
+
+	assert.True(t, IsCommand("balance"))
+	assert.False(t, IsCommand("-wallet"))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, exitCode(nil))
+	assert.Equal(t, ExitError, exitCode(errors.New("failed")))
+	assert.Equal(t, ExitRejected, exitCode(exitErrorf(ExitRejected, "rejected")))
+	assert.Equal(t, ExitConnection, exitCode(status.Error(codes.Unavailable, "connection refused")))
+}
+
+func newOptions(args ...string) *options {
+	o := &options{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.register(fs)
+	_ = fs.Parse(args)
+	return o
+}
+
+func TestPassword(t *testing.T) {
+	const env = "CLI_WALLET_TEST_PASSWORD"
+	os.Unsetenv(env)
+	_, err := newOptions("-password-env", env).password()
+	assert.Equal(t, ExitWallet, exitCode(err))
+
+	os.Setenv(env, "from env")
+	defer os.Unsetenv(env)
+	p, err := newOptions("-password-env", env).password()
+	require.NoError(t, err)
+	assert.Equal(t, "from env", p)
+
+	file := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, ioutil.WriteFile(file, []byte("from file\n"), 0600))
+	p, err = newOptions("-password-env", env, "-password-file", file).password()
+	require.NoError(t, err)
+	assert.Equal(t, "from file", p)
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = w.WriteString("from fd\n")
+	require.NoError(t, err)
+	w.Close()
+	p, err = newOptions("-password-file", file, "-password-fd", strconv.Itoa(int(r.Fd()))).password()
+	require.NoError(t, err)
+	assert.Equal(t, "from fd", p)
+}
+
+func TestApprovalPassword(t *testing.T) {
+	const env = "CLI_WALLET_TEST_APPROVAL_PASSWORD"
+	os.Unsetenv(env)
+	_, err := approvalPassword("", env)
+	assert.Equal(t, ExitUsage, exitCode(err))
+
+	os.Setenv(env, "second")
+	defer os.Unsetenv(env)
+	p, err := approvalPassword("", env)
+	require.NoError(t, err)
+	assert.Equal(t, "second", p)
+
+	file := filepath.Join(t.TempDir(), "approval")
+	require.NoError(t, ioutil.WriteFile(file, []byte("from file\n"), 0600))
+	p, err = approvalPassword(file, env)
+	require.NoError(t, err)
+	assert.Equal(t, "from file", p)
+}
+
+func TestRunJSONError(t *testing.T) {
+	var out, errOut bytes.Buffer
+	assert.Equal(t, ExitUsage, Run([]string{"tx-status", "-json"}, &out, &errOut))
+	assert.JSONEq(t, `{"error": "-id is required"}`, out.String())
+	assert.Empty(t, errOut.String())
+}
+
+func TestKnownServers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "known_servers.json")
+	k, err := common.LoadKnownServers(file)
+	require.NoError(t, err)
+	_, err = k.Check("api.example.org:443", "SHA256:one", "CN=api.example.org")
+	require.NoError(t, err)
+
+	var out, errOut bytes.Buffer
+	assert.Equal(t, ExitOK, Run([]string{"known-servers", "-known-servers", file, "-json"}, &out, &errOut))
+	assert.Contains(t, out.String(), `"fingerprint": "SHA256:one"`)
+
+	out.Reset()
+	assert.Equal(t, ExitOK, Run([]string{"forget-server", "-known-servers", file, "-server", "api.example.org:443"}, &out, &errOut))
+	assert.Contains(t, out.String(), "No known servers")
+	assert.Equal(t, ExitNotFound, Run([]string{"forget-server", "-known-servers", file, "-server", "api.example.org:443"}, &out, &errOut))
+
+	assert.Equal(t, ExitUsage, Run([]string{"trust-server", "-known-servers", file, "-server", "a:443,b:443"}, &out, &errOut))
+}
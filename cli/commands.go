@@ -0,0 +1,399 @@
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spacemeshos/CLIWallet/client"
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/output"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/common/util"
+)
+
+// account returns the wallet account named by nameOrAddress, or the current account if it is empty
+func account(be *client.WalletBackend, nameOrAddress string) (*common.LocalAccount, error) {
+	if nameOrAddress == "" {
+		return be.CurrentAccount()
+	}
+	if addr, err := common.ParseAddress(nameOrAddress); err == nil {
+		return be.AccountByAddress(addr)
+	}
+	accounts, err := be.Accounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, acc := range accounts {
+		if acc.Name == nameOrAddress {
+			return acc, nil
+		}
+	}
+	return nil, exitErrorf(ExitUsage, "no account named %s in the wallet", nameOrAddress)
+}
+
+func runAccounts(o *options, args []string, out io.Writer) error {
+	if err := o.parse(args); err != nil {
+		return err
+	}
+	be, err := o.openWallet()
+	if err != nil {
+		return err
+	}
+	accounts, err := be.Accounts()
+	if err != nil {
+		return err
+	}
+	v := output.Accounts{Accounts: make([]output.AccountSummary, len(accounts))}
+	for i, acc := range accounts {
+		v.Accounts[i] = output.AccountSummary{Name: acc.Name, Address: acc.Address().String()}
+	}
+	return o.render(out, v)
+}
+
+func runBalance(o *options, args []string, out io.Writer) error {
+	var accountFlag, addressFlag string
+	o.fs.StringVar(&accountFlag, "account", "", "Wallet account name or address. Defaults to the current account")
+	o.fs.StringVar(&addressFlag, "address", "", "Any account address. No wallet is needed")
+	if err := o.parse(args); err != nil {
+		return err
+	}
+
+	var be *client.WalletBackend
+	var address gosmtypes.Address
+	var err error
+	if addressFlag != "" {
+		if address, err = common.ParseAddress(addressFlag); err != nil {
+			return &exitError{ExitUsage, err}
+		}
+		if be, err = o.connect(); err != nil {
+			return err
+		}
+	} else {
+		if be, err = o.openWallet(); err != nil {
+			return err
+		}
+		acc, err := account(be, accountFlag)
+		if err != nil {
+			return err
+		}
+		address = acc.Address()
+	}
+
+	state, err := be.AccountState(address)
+	if err != nil {
+		return err
+	}
+	v := output.Account{
+		Address:          address.String(),
+		Balance:          state.StateCurrent.GetBalance().GetValue(),
+		Nonce:            state.StateCurrent.GetCounter(),
+		ProjectedBalance: state.StateProjected.GetBalance().GetValue(),
+		ProjectedNonce:   state.StateProjected.GetCounter(),
+	}
+	if o.json {
+		return o.render(out, v)
+	}
+	// the text output of balance predates the views and scripts parse it
+	fmt.Fprintln(out, "address:", v.Address)
+	fmt.Fprintln(out, "balance:", v.Balance)
+	fmt.Fprintln(out, "projected balance:", v.ProjectedBalance)
+	fmt.Fprintln(out, "nonce:", v.Nonce)
+	fmt.Fprintln(out, "projected nonce:", v.ProjectedNonce)
+	return nil
+}
+
+func runSend(o *options, args []string, out io.Writer) error {
+	var accountFlag, to, amountFlag, feeFlag, approvalEnv, approvalFile string
+	var gasLimit uint64
+	var dryRun bool
+	o.fs.StringVar(&accountFlag, "account", "", "Wallet account name or address to send from. Defaults to the current account")
+	o.fs.StringVar(&to, "to", "", "Recipient address")
+	o.fs.StringVar(&amountFlag, "amount", "", "Amount in Smidge, or SMH e.g. 2.5SMH")
+	o.fs.StringVar(&feeFlag, "fee", common.FeeNormal, "Fee in Smidge, or one of low, normal and fast. Defaults to the fee of the profile")
+	o.fs.Uint64Var(&gasLimit, "gas-limit", common.DefaultGasLimit, "Gas limit")
+	o.fs.BoolVar(&dryRun, "dry-run", false, "Check the transfer without sending it")
+	o.fs.StringVar(&approvalEnv, "approval-password-env", DefaultApprovalPasswordEnv, "Environment variable holding the second password of the spending policy")
+	o.fs.StringVar(&approvalFile, "approval-password-file", "", "File holding the second password of the spending policy")
+	if err := o.parse(args); err != nil {
+		return err
+	}
+	if !o.isSet("fee") {
+		feeFlag = o.profile.Fee
+	}
+	if to == "" || amountFlag == "" {
+		return exitErrorf(ExitUsage, "-to and -amount are required")
+	}
+	recipient, err := common.ParseAddress(to)
+	if err != nil {
+		return &exitError{ExitUsage, err}
+	}
+	amount, err := common.ParseAmount(amountFlag)
+	if err != nil {
+		return &exitError{ExitUsage, err}
+	}
+
+	be, err := o.openWallet()
+	if err != nil {
+		return err
+	}
+	acc, err := account(be, accountFlag)
+	if err != nil {
+		return err
+	}
+	fee, err := fee(be, feeFlag)
+	if err != nil {
+		return err
+	}
+	if _, _, err := be.ReconcilePendingTransactions(acc.Address()); err != nil {
+		return err
+	}
+	nonce, err := be.NextNonce(acc.Address())
+	if err != nil {
+		return err
+	}
+
+	preflight, err := be.PreflightTransfer(acc.Address(), recipient, amount, fee, nonce)
+	if err != nil {
+		return err
+	}
+	v := output.Transfer{Nonce: nonce, Fee: fee, DryRun: dryRun, Warnings: preflight.Warnings}
+	if v.Warnings == nil {
+		v.Warnings = []string{}
+	}
+	if preflight.Insufficient {
+		v.DryRun = false
+		if !o.json {
+			o.render(out, v)
+		}
+		return exitErrorf(ExitRejected, "insufficient funds")
+	}
+	if dryRun {
+		return o.render(out, v)
+	}
+
+	policy, err := be.SpendingPolicy(acc.Address())
+	if err != nil {
+		return err
+	}
+	if policy != nil && policy.NeedsApproval(amount) {
+		password, err := approvalPassword(approvalFile, approvalEnv)
+		if err != nil {
+			return err
+		}
+		if err := be.ApproveTransfer(acc.Address(), recipient, amount, password); err != nil {
+			return &exitError{ExitRejected, err}
+		}
+	}
+
+	txState, err := be.Transfer(recipient, nonce, amount, fee, gasLimit, acc.PrivKey)
+	var violation *common.PolicyViolation
+	if errors.As(err, &violation) {
+		return &exitError{ExitRejected, err}
+	}
+	if err != nil {
+		return err
+	}
+	v.Id = "0x" + hex.EncodeToString(txState.Id.Id)
+	v.State = common.TxStateName(txState.State)
+	if err := o.render(out, v); err != nil {
+		return err
+	}
+	return exitErrorForState(txState.State, ExitOK)
+}
+
+// fee returns the fee given as a number of Smidge or as a preset estimated from recent transactions
+func fee(be *client.WalletBackend, s string) (uint64, error) {
+	if f, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return f, nil
+	}
+	for _, p := range common.FeePresets {
+		if p == s {
+			estimate, err := be.EstimateFees()
+			if err != nil {
+				return 0, err
+			}
+			return estimate.Preset(s), nil
+		}
+	}
+	return 0, exitErrorf(ExitUsage, "invalid fee %s", s)
+}
+
+func runTxStatus(o *options, args []string, out io.Writer) error {
+	var id string
+	o.fs.StringVar(&id, "id", "", "Transaction id")
+	if err := o.parse(args); err != nil {
+		return err
+	}
+	if id == "" {
+		return exitErrorf(ExitUsage, "-id is required")
+	}
+	be, err := o.connect()
+	if err != nil {
+		return err
+	}
+
+	txId := util.FromHex(id)
+	txState, tx, err := be.TransactionState(txId, true)
+	if err != nil {
+		return err
+	}
+	if txState == nil || txState.State == apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED {
+		return exitErrorf(ExitNotFound, "unknown transaction %s", id)
+	}
+	v := output.TxStatus{Id: "0x" + hex.EncodeToString(txId), State: common.TxStateName(txState.State)}
+	if tx != nil {
+		sender := gosmtypes.BytesToAddress(tx.Sender.GetAddress())
+		v.From = sender.String()
+		if ct := tx.GetCoinTransfer(); ct != nil {
+			v.To = gosmtypes.BytesToAddress(ct.Receiver.GetAddress()).String()
+		}
+		v.Amount = tx.Amount.GetValue()
+		v.Fee = tx.GasOffered.GetGasPrice()
+		v.Nonce = tx.Counter
+		if receipt, err := be.TransactionReceipt(sender, txId); err == nil && receipt != nil {
+			v.Receipt = &output.Receipt{
+				Result:  common.TxResultText(receipt.Result),
+				Layer:   receipt.Layer.GetNumber(),
+				GasUsed: receipt.GasUsed,
+				FeePaid: receipt.Fee.GetValue(),
+			}
+		}
+	}
+	if err := o.render(out, v); err != nil {
+		return err
+	}
+	return exitErrorForState(txState.State, ExitPending)
+}
+
+func runNodeStatus(o *options, args []string, out io.Writer) error {
+	if err := o.parse(args); err != nil {
+		return err
+	}
+	be, err := o.connect()
+	if err != nil {
+		return err
+	}
+	s, err := be.NodeStatus()
+	if err != nil {
+		return err
+	}
+	v := output.Node{
+		Synced:        s.IsSynced,
+		SyncedLayer:   s.SyncedLayer.GetNumber(),
+		TopLayer:      s.TopLayer.GetNumber(),
+		VerifiedLayer: s.VerifiedLayer.GetNumber(),
+		Peers:         s.ConnectedPeers,
+	}
+	if o.json {
+		if err := o.render(out, v); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(out, "synced:", v.Synced)
+		fmt.Fprintln(out, "synced layer:", v.SyncedLayer)
+		fmt.Fprintln(out, "top layer:", v.TopLayer)
+		fmt.Fprintln(out, "verified layer:", v.VerifiedLayer)
+		fmt.Fprintln(out, "peers:", v.Peers)
+	}
+	if !s.IsSynced {
+		return exitErrorf(ExitPending, "the node is not synced")
+	}
+	return nil
+}
+
+// exitErrorForState returns the error for a transaction in state s.
+// Transactions which are not processed yet end with pendingCode.
+func exitErrorForState(s apitypes.TransactionState_TransactionState, pendingCode int) error {
+	switch s {
+	case apitypes.TransactionState_TRANSACTION_STATE_PROCESSED:
+		return nil
+	case apitypes.TransactionState_TRANSACTION_STATE_REJECTED,
+		apitypes.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS,
+		apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING:
+		return exitErrorf(ExitRejected, "transaction %s", common.TxStateName(s))
+	}
+	if pendingCode == ExitOK {
+		return nil
+	}
+	return exitErrorf(pendingCode, "transaction not processed yet")
+}
+
+// knownServers loads the known servers file of the profile. Pinning doesn't need to be on, so that pins can be managed beforehand.
+func (o *options) knownServers() (*common.KnownServers, error) {
+	k, err := common.LoadKnownServers(o.profile.KnownServers)
+	if err != nil {
+		return nil, &exitError{ExitUsage, err}
+	}
+	return k, nil
+}
+
+// pinnedServer returns the server whose pin a subcommand changes
+func (o *options) pinnedServer() (string, error) {
+	if len(o.profile.Servers) != 1 {
+		return "", exitErrorf(ExitUsage, "the profile has %d servers. Select one with -server", len(o.profile.Servers))
+	}
+	return o.profile.Servers[0], nil
+}
+
+func runKnownServers(o *options, args []string, out io.Writer) error {
+	if err := o.parse(args); err != nil {
+		return err
+	}
+	k, err := o.knownServers()
+	if err != nil {
+		return err
+	}
+	return o.render(out, output.NewKnownServers(k.List()))
+}
+
+func runTrustServer(o *options, args []string, out io.Writer) error {
+	var fingerprintFlag string
+	o.fs.StringVar(&fingerprintFlag, "fingerprint", "", "Expected fingerprint of the public key. Without it, the key is displayed but not trusted")
+	if err := o.parse(args); err != nil {
+		return err
+	}
+	server, err := o.pinnedServer()
+	if err != nil {
+		return err
+	}
+	k, err := o.knownServers()
+	if err != nil {
+		return err
+	}
+	fingerprint, subject, err := client.FetchServerKey(server, o.connection)
+	if err != nil {
+		return &exitError{ExitConnection, fmt.Errorf("failed to get the public key of %s: %v", server, err)}
+	}
+	if fingerprintFlag == "" {
+		return exitErrorf(ExitUsage, "%s presents the public key %s (%s). Trust it with -fingerprint %s", server, fingerprint, subject, fingerprint)
+	}
+	if fingerprintFlag != fingerprint {
+		return exitErrorf(ExitRejected, "%s presents the public key %s, not %s", server, fingerprint, fingerprintFlag)
+	}
+	if err := k.Trust(server, fingerprint, subject); err != nil {
+		return err
+	}
+	return o.render(out, output.NewKnownServers(k.List()))
+}
+
+func runForgetServer(o *options, args []string, out io.Writer) error {
+	if err := o.parse(args); err != nil {
+		return err
+	}
+	server, err := o.pinnedServer()
+	if err != nil {
+		return err
+	}
+	k, err := o.knownServers()
+	if err != nil {
+		return err
+	}
+	if err := k.Forget(server); err != nil {
+		return exitErrorf(ExitNotFound, "%v", err)
+	}
+	return o.render(out, output.NewKnownServers(k.List()))
+}
@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/spacemeshos/CLIWallet/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultAuthHeader is the metadata key of the token when no header is set
+const DefaultAuthHeader = "authorization"
+
+// AuthOptions are the credentials sent to the API servers in the metadata of each call, such as the
+// API key of a hosted server. The token is the static token, or is read from a file or an environment
+// variable, or is printed by the refresh command.
+type AuthOptions struct {
+	Token     string `mapstructure:"token"`
+	TokenFile string `mapstructure:"token_file"` // read again when a server rejects the token
+	TokenEnv  string `mapstructure:"token_env"`  // name of the environment variable holding the token
+	// command which prints a new token, run when a server rejects the token or when there is no other token
+	RefreshCommand string `mapstructure:"refresh_command"`
+	Header         string `mapstructure:"header"` // metadata key of the token, authorization by default
+	Scheme         string `mapstructure:"scheme"` // sent before the token, Bearer by default in the authorization header
+}
+
+// IsSet returns true iff the options set a token
+func (o AuthOptions) IsSet() bool {
+	return o.Token != "" || o.TokenFile != "" || o.TokenEnv != "" || o.RefreshCommand != ""
+}
+
+// Validate checks that the options set one token at most
+func (o AuthOptions) Validate() error {
+	sources := 0
+	for _, s := range []string{o.Token, o.TokenFile, o.TokenEnv} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("set only one of token, token_file and token_env")
+	}
+	if !o.IsSet() && (o.Header != "" || o.Scheme != "") {
+		return errors.New("the auth header and scheme require a token")
+	}
+	return nil
+}
+
+// header returns the metadata key of the token and the scheme sent before it
+func (o AuthOptions) header() (key, scheme string) {
+	key, scheme = strings.ToLower(o.Header), o.Scheme
+	if key == "" {
+		key = DefaultAuthHeader
+	}
+	if key == DefaultAuthHeader && scheme == "" {
+		scheme = "Bearer"
+	}
+	return key, scheme
+}
+
+// tokenSource holds the token sent to the servers, and gets a new one when a server rejects it
+type tokenSource struct {
+	options AuthOptions
+
+	mu    sync.Mutex
+	token string
+	stale bool // a server rejected the token during a stream
+}
+
+func newTokenSource(options AuthOptions) *tokenSource {
+	return &tokenSource{options: options}
+}
+
+// read reads the token from its source. refresh is set when a server rejected the previous token.
+func (s *tokenSource) read(ctx context.Context, refresh bool) (string, error) {
+	o := s.options
+	if o.RefreshCommand != "" && (refresh || o.Token == "" && o.TokenFile == "" && o.TokenEnv == "") {
+		return runRefreshCommand(ctx, o.RefreshCommand)
+	}
+	switch {
+	case o.TokenFile != "":
+		b, err := ioutil.ReadFile(o.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the token file: %v", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	case o.TokenEnv != "":
+		token := strings.TrimSpace(os.Getenv(o.TokenEnv))
+		if token == "" {
+			return "", fmt.Errorf("the token variable %s is not set", o.TokenEnv)
+		}
+		return token, nil
+	}
+	return o.Token, nil
+}
+
+// runRefreshCommand runs a command with the shell and returns the token it prints
+func runRefreshCommand(ctx context.Context, command string) (string, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	out, err := exec.CommandContext(ctx, shell, flag, command).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			err = fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("the token refresh command failed: %v", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", errors.New("the token refresh command printed no token")
+	}
+	return token, nil
+}
+
+// get returns the current token, reading it on first use and after it was rejected
+func (s *tokenSource) get(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && !s.stale {
+		return s.token, nil
+	}
+	token, err := s.read(ctx, s.stale)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.stale = token, false
+	return token, nil
+}
+
+// refresh replaces a token a server rejected, unless another call already replaced it
+func (s *tokenSource) refresh(ctx context.Context, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != rejected && !s.stale {
+		return s.token, nil
+	}
+	token, err := s.read(ctx, true)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.stale = token, false
+	return token, nil
+}
+
+// expire marks a token a server rejected, so that the next call gets a new one
+func (s *tokenSource) expire(rejected string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == rejected {
+		s.stale = true
+	}
+}
+
+// withToken adds a token to the outgoing metadata of a call
+func (s *tokenSource) withToken(ctx context.Context, token string) context.Context {
+	key, scheme := s.options.header()
+	if scheme != "" {
+		token = scheme + " " + token
+	}
+	return metadata.AppendToOutgoingContext(ctx, key, token)
+}
+
+// unaryInterceptor sends the token with a call. When the server rejects it, the call is sent again
+// once with a new token: the server didn't process it.
+func (s *tokenSource) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	token, err := s.get(ctx)
+	if err != nil {
+		return status.Errorf(codes.Unauthenticated, "failed to get the API token: %v", err)
+	}
+	err = invoker(s.withToken(ctx, token), method, req, reply, cc, opts...)
+	if status.Code(err) != codes.Unauthenticated {
+		return err
+	}
+	newToken, refreshErr := s.refresh(ctx, token)
+	if refreshErr != nil {
+		log.Warning("failed to refresh the API token: %v", refreshErr)
+		return err
+	}
+	if newToken == token {
+		return err
+	}
+	return invoker(s.withToken(ctx, newToken), method, req, reply, cc, opts...)
+}
+
+// streamInterceptor sends the token with a stream. A stream can't be sent again, so when the server
+// rejects the token, the next call gets a new one.
+func (s *tokenSource) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	token, err := s.get(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "failed to get the API token: %v", err)
+	}
+	stream, err := streamer(s.withToken(ctx, token), desc, cc, method, opts...)
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			s.expire(token)
+		}
+		return nil, err
+	}
+	return &authStream{ClientStream: stream, source: s, token: token}, nil
+}
+
+// authStream is a stream which expires its token when the server rejects it
+type authStream struct {
+	grpc.ClientStream
+	source *tokenSource
+	token  string
+}
+
+func (a *authStream) RecvMsg(m interface{}) error {
+	err := a.ClientStream.RecvMsg(m)
+	if status.Code(err) == codes.Unauthenticated {
+		a.source.expire(a.token)
+	}
+	return err
+}
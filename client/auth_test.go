@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authServer is an API server which only answers the calls with the accepted token
+type authServer struct {
+	addr   string
+	header string
+
+	mu         sync.Mutex
+	accepted   string
+	tokens     []string // tokens of the calls
+	userAgents []string
+}
+
+func (a *authServer) accept(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.accepted = token
+}
+
+func (a *authServer) Tokens() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string{}, a.tokens...)
+}
+
+func (a *authServer) check(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	token := strings.Join(md.Get(a.header), ",")
+	a.tokens = append(a.tokens, token)
+	a.userAgents = append(a.userAgents, md.Get("user-agent")...)
+	if token != a.accepted {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
+
+// startAuthServer starts an API server which requires the accepted token in the header metadata
+func startAuthServer(t *testing.T, header, accepted string) *authServer {
+	a := &authServer{header: header, accepted: accepted}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := a.check(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := a.check(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	apitypes.RegisterNodeServiceServer(s, &testNode{synced: true})
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	a.addr = l.Addr().String()
+	return a
+}
+
+func TestAuthToken(t *testing.T) {
+	a := startAuthServer(t, "authorization", "Bearer secret")
+	c := newGRPCClient(ConnectionOptions{Servers: []string{a.addr}, Auth: AuthOptions{Token: "secret"}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	require.NoError(t, c.Echo())
+	require.NotEmpty(t, a.userAgents)
+	assert.True(t, strings.HasPrefix(a.userAgents[0], "sm-cli-wallet/"+userAgentVersion()), a.userAgents[0])
+
+	// an API key in another header
+	a = startAuthServer(t, "x-api-key", "key")
+	c = newGRPCClient(ConnectionOptions{Servers: []string{a.addr}, Auth: AuthOptions{Token: "key", Header: "X-API-Key"}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	assert.NoError(t, c.Echo())
+
+	// without a token
+	c = newGRPCClient(ConnectionOptions{Servers: []string{a.addr}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	assert.Equal(t, codes.Unauthenticated, status.Code(c.Echo()))
+}
+
+func TestAuthTokenEnv(t *testing.T) {
+	a := startAuthServer(t, "authorization", "Bearer from-env")
+	c := newGRPCClient(ConnectionOptions{Servers: []string{a.addr}, Auth: AuthOptions{TokenEnv: "CLI_WALLET_TEST_TOKEN"}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	setenv(t, "CLI_WALLET_TEST_TOKEN", "")
+	err := c.Echo()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Contains(t, err.Error(), "CLI_WALLET_TEST_TOKEN is not set")
+	assert.Empty(t, a.Tokens(), "nothing is sent without a token")
+
+	setenv(t, "CLI_WALLET_TEST_TOKEN", "from-env\n")
+	assert.NoError(t, c.Echo())
+}
+
+func TestAuthTokenFileIsReadAgain(t *testing.T) {
+	a := startAuthServer(t, "authorization", "Bearer one")
+	file := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(file, []byte("one\n"), 0600))
+	c := newGRPCClient(ConnectionOptions{Servers: []string{a.addr}, Auth: AuthOptions{TokenFile: file}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	require.NoError(t, c.Echo())
+
+	// the token is rotated: the rejected call is sent again with the new token
+	a.accept("Bearer two")
+	require.NoError(t, ioutil.WriteFile(file, []byte("two\n"), 0600))
+	require.NoError(t, c.Echo())
+	assert.Equal(t, []string{"Bearer one", "Bearer one", "Bearer two"}, a.Tokens())
+}
+
+func TestAuthRefreshCommand(t *testing.T) {
+	dir := t.TempDir()
+	a := startAuthServer(t, "authorization", "Bearer fresh")
+	c := newGRPCClient(ConnectionOptions{Servers: []string{a.addr}, Auth: AuthOptions{
+		Token:          "expired",
+		RefreshCommand: "echo fresh",
+	}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	require.NoError(t, c.Echo())
+	assert.Equal(t, []string{"Bearer expired", "Bearer fresh"}, a.Tokens())
+
+	// a stream rejected by the server gets a new token for the next call
+	a.accept("Bearer fresher")
+	c.auth.options.RefreshCommand = "echo fresher"
+	stream, err := c.getNodeServiceClient().StatusStream(context.Background(), &apitypes.StatusStreamRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.NoError(t, c.Echo())
+	assert.Equal(t, []string{"Bearer fresh", "Bearer fresher"}, a.Tokens()[2:])
+
+	// a failing refresh command keeps the error of the server
+	a.accept("Bearer other")
+	c.auth.options.RefreshCommand = "echo no token >&2; exit 1"
+	err = c.Echo()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Contains(t, err.Error(), "invalid token")
+	_, err = runRefreshCommand(context.Background(), c.auth.options.RefreshCommand)
+	assert.Contains(t, err.Error(), "no token")
+
+	// the refresh command is the token source when there is no other
+	c = newGRPCClient(ConnectionOptions{Servers: []string{a.addr}, Auth: AuthOptions{RefreshCommand: "cat " + filepath.Join(dir, "missing")}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	assert.Equal(t, codes.Unauthenticated, status.Code(c.Echo()))
+}
+
+func TestAuthOptionsValidate(t *testing.T) {
+	assert.NoError(t, AuthOptions{Token: "secret", RefreshCommand: "get-token"}.Validate())
+	assert.Error(t, AuthOptions{Token: "secret", TokenFile: "token"}.Validate())
+	assert.Error(t, AuthOptions{Header: "x-api-key"}.Validate())
+
+	key, scheme := AuthOptions{Token: "secret"}.header()
+	assert.Equal(t, "authorization", key)
+	assert.Equal(t, "Bearer", scheme)
+	key, scheme = AuthOptions{Token: "secret", Header: "X-API-Key"}.header()
+	assert.Equal(t, "x-api-key", key)
+	assert.Empty(t, scheme)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -29,6 +30,16 @@ type WalletBackend struct {
 
 	wallet *smWallet.Wallet
 	open   bool
+
+	journalMu sync.Mutex
+	journal   *common.TxJournal
+
+	// sendMu serializes the nonce allocation, submission and journal write of the transactions sent
+	// by the REPL and the scheduler
+	sendMu sync.Mutex
+
+	approvalMu sync.Mutex
+	approval   *transferApproval
 	//currentAccount   *common.LocalAccount
 }
 
@@ -73,10 +84,10 @@ func getPassword() (string, error) {
 	return getString("Enter wallet file password: ")
 }
 
-// OpenConnection opens a connection but not the wallet
-func OpenConnection(grpcServer string, secureConnection bool, wd string) (wbx *WalletBackend, err error) {
+// OpenConnection connects to the API servers, in order of preference, but doesn't open a wallet
+func OpenConnection(options ConnectionOptions, wd string) (wbx *WalletBackend, err error) {
 	wbe := WalletBackend{workingDirectory: wd}
-	wbe.gRPCClient = newGRPCClient(grpcServer, secureConnection)
+	wbe.gRPCClient = newGRPCClient(options)
 	if err = wbe.gRPCClient.Connect(); err != nil {
 		// failed to connect to grpc server
 		log.Error("failed to connect to the grpc server: %s", err)
@@ -85,6 +96,11 @@ func OpenConnection(grpcServer string, secureConnection bool, wd string) (wbx *W
 	return &wbe, nil
 }
 
+// SetWorkingDirectory sets the directory wallets are opened from and created in
+func (w *WalletBackend) SetWorkingDirectory(dir string) {
+	w.workingDirectory = dir
+}
+
 func accounts(num int) string {
 	if num == 1 {
 		return "1 account"
@@ -121,30 +137,38 @@ func (w *WalletBackend) OpenWallet() bool {
 }
 
 // OpenWalletBackend  open an existing wallet
-func OpenWalletBackend(wallet string, grpcServer string, secureConnection bool) (wbx *WalletBackend, err error) {
-	var wbe WalletBackend
-	wbx = nil
-	if wbe.wallet, err = smWallet.LoadWallet(wallet); err != nil {
-		return
-	}
+func OpenWalletBackend(wallet string, options ConnectionOptions) (wbx *WalletBackend, err error) {
 	password, err := getPassword()
 	if err != nil {
 		return
 	}
 	fmt.Println("\nloading...")
-	if err = wbe.wallet.Unlock(password); err != nil {
-		return
+	if wbx, err = OpenWalletBackendWithPassword(wallet, password, options); err != nil {
+		return nil, err
 	}
-	ne, err := wbe.wallet.GetNumberOfAccounts()
+	ne, err := wbx.wallet.GetNumberOfAccounts()
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println(wbe.wallet.Meta.DisplayName, "successfully opened with", accounts(ne))
-	wbe.gRPCClient = newGRPCClient(grpcServer, secureConnection)
+	fmt.Println(wbx.wallet.Meta.DisplayName, "successfully opened with", accounts(ne))
+	return wbx, nil
+}
+
+// OpenWalletBackendWithPassword opens an existing wallet with the given password and connects to the grpc server
+func OpenWalletBackendWithPassword(wallet string, password string, options ConnectionOptions) (*WalletBackend, error) {
+	var wbe WalletBackend
+	var err error
+	if wbe.wallet, err = smWallet.LoadWallet(wallet); err != nil {
+		return nil, err
+	}
+	if err = wbe.wallet.Unlock(password); err != nil {
+		return nil, err
+	}
+	wbe.gRPCClient = newGRPCClient(options)
 	if err = wbe.gRPCClient.Connect(); err != nil {
 		// failed to connect to grpc server
 		log.Error("failed to connect to the grpc server: %s", err)
-		return
+		return nil, err
 	}
 	wbe.open = true
 	return &wbe, nil
@@ -183,7 +207,7 @@ func (w *WalletBackend) NewWallet() bool {
 }
 
 // NewWalletBackend set up a wallet -
-func NewWalletBackend(walletName string, grpcServer string, secureConnection bool) (wbx *WalletBackend, err error) {
+func NewWalletBackend(walletName string, options ConnectionOptions) (wbx *WalletBackend, err error) {
 	var wbe WalletBackend
 	wbx = nil
 	password, err := getPassword()
@@ -200,7 +224,7 @@ func NewWalletBackend(walletName string, grpcServer string, secureConnection boo
 	}
 
 	fmt.Println(wbe.wallet.Meta.DisplayName, "successfully created")
-	wbe.gRPCClient = newGRPCClient(grpcServer, secureConnection)
+	wbe.gRPCClient = newGRPCClient(options)
 	if err = wbe.gRPCClient.Connect(); err != nil {
 		// failed to connect to grpc server
 		log.Error("failed to connect to the grpc server: %s", err)
@@ -211,7 +235,13 @@ func NewWalletBackend(walletName string, grpcServer string, secureConnection boo
 }
 
 func (w *WalletBackend) CloseWallet() {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	w.journalMu.Lock()
+	defer w.journalMu.Unlock()
 	w.wallet = nil
+	w.journal = nil
+	w.approval = nil
 }
 
 // CurrentAccount - get the latest account into cli-wallet format
@@ -255,8 +285,71 @@ func (w *WalletBackend) StoreAccounts() error {
 	return w.wallet.SaveWallet()
 }
 
+// caller is how the transfers are sent: the client their calls go through, and how the errors after
+// their submission are logged. The REPL sends them in the foreground, where Ctrl-C cancels the calls
+// and the errors fail the command, and the scheduler in the background.
+type caller struct {
+	api      *gRPCClient
+	logError func(format string, args ...interface{})
+}
+
+func (w *WalletBackend) foreground() caller {
+	return caller{api: w.gRPCClient, logError: log.Error}
+}
+
+func (w *WalletBackend) background() caller {
+	return caller{api: w.detached(), logError: log.BackgroundError}
+}
+
 // Transfer creates a sign coin transaction and submits it
 func (w *WalletBackend) Transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*pb.TransactionState, error) {
+	return w.transfer(recipient, nonce, amount, gasPrice, gasLimit, key, "", true)
+}
+
+// transferNext sends a coin transaction with the next nonce of the sender, unless the sender can't pay for it.
+// The nonce is allocated and the transaction submitted atomically, so that concurrent transfers don't use the same nonce.
+func (w *WalletBackend) transferNext(c caller, recipient gosmtypes.Address, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*pb.TransactionState, error) {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	sender := smWallet.Address(key)
+	nonce, err := w.nextNonce(c.api, sender)
+	if err != nil {
+		return nil, err
+	}
+	preflight, err := w.preflightFunds(c.api, sender, amount, gasPrice, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if err := insufficientFunds(preflight); err != nil {
+		return nil, err
+	}
+	return w.submitTransfer(c, recipient, nonce, amount, gasPrice, gasLimit, key, "", true)
+}
+
+// transfer signs and submits a coin transaction and records it in the journal,
+// noting the id of the transaction it replaces if any.
+func (w *WalletBackend) transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey, replaces string, checkPolicy bool) (*pb.TransactionState, error) {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	return w.submitTransfer(w.foreground(), recipient, nonce, amount, gasPrice, gasLimit, key, replaces, checkPolicy)
+}
+
+// submitTransfer signs, submits and records a coin transaction. w.sendMu must be held.
+// checkPolicy is only unset for replacements of transactions which were checked as the original.
+// The transaction is submitted when it can't be recorded, so the recording errors are logged by c.
+func (w *WalletBackend) submitTransfer(c caller, recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey, replaces string, checkPolicy bool) (*pb.TransactionState, error) {
+	sender := smWallet.Address(key)
+	if replaces == "" {
+		if err := w.checkNonceUnused(sender, nonce); err != nil {
+			return nil, err
+		}
+	}
+	if checkPolicy {
+		if err := w.checkSpendingPolicy(sender, recipient, amount, gasPrice); err != nil {
+			return nil, err
+		}
+	}
+
 	tx := common.SerializableSignedTransaction{}
 	tx.AccountNonce = nonce
 	tx.Amount = amount
@@ -270,7 +363,53 @@ func (w *WalletBackend) Transfer(recipient gosmtypes.Address, nonce, amount, gas
 	if err != nil {
 		return nil, err
 	}
-	return w.SubmitCoinTransaction(b)
+	txState, err := c.api.SubmitTransaction(b)
+	if err != nil {
+		return nil, err
+	}
+	if replaces == "" {
+		if err := w.recordSpend(sender, amount+gasPrice); err != nil {
+			c.logError("failed to record the transfer for the daily limit: %v", err)
+		}
+	}
+	if err := w.recordTransaction(sender, txState, &tx.InnerSerializableSignedTransaction, replaces); err != nil {
+		c.logError("%v", err)
+	}
+	return txState, nil
+}
+
+// SubmitContractTransaction signs and submits an app call, app spawn or template deployment
+// and records it in the journal. The spending policy applies to it as to a transfer to its app or template.
+func (w *WalletBackend) SubmitContractTransaction(tx common.InnerSerializableContractTransaction, key ed25519.PrivateKey) (*pb.TransactionState, error) {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	c := w.foreground()
+	sender := smWallet.Address(key)
+	if err := w.checkNonceUnused(sender, tx.AccountNonce); err != nil {
+		return nil, err
+	}
+	if err := w.checkSpendingPolicy(sender, tx.Account, tx.Amount, tx.Price); err != nil {
+		return nil, err
+	}
+
+	signed := common.SerializableSignedContractTransaction{InnerSerializableContractTransaction: tx}
+	buf, _ := interfaceToBytes(&signed.InnerSerializableContractTransaction)
+	copy(signed.Signature[:], ed25519.Sign2(key, buf))
+	b, err := interfaceToBytes(&signed)
+	if err != nil {
+		return nil, err
+	}
+	txState, err := c.api.SubmitTransaction(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.recordSpend(sender, tx.Amount+tx.Price); err != nil {
+		c.logError("failed to record the transaction for the daily limit: %v", err)
+	}
+	if err := w.recordContractTransaction(sender, txState, &tx); err != nil {
+		c.logError("%v", err)
+	}
+	return txState, nil
 }
 
 func (w *WalletBackend) GetAccount(accountName string) (*common.LocalAccount, error) {
@@ -299,6 +438,50 @@ func (w *WalletBackend) GetAccount(accountName string) (*common.LocalAccount, er
 	return nil, err
 }
 
+// AccountByAddress returns the wallet account with the given address
+func (w *WalletBackend) AccountByAddress(address gosmtypes.Address) (*common.LocalAccount, error) {
+	numberOfAccounts, err := w.wallet.GetNumberOfAccounts()
+	if err != nil {
+		return nil, err
+	}
+	for j := 0; j < numberOfAccounts; j++ {
+		if addr, err := w.wallet.GetAddress(j); err != nil || addr != address {
+			continue
+		}
+		dn, err := w.wallet.GetAccountDisplayName(j)
+		if err != nil {
+			return nil, err
+		}
+		pk, err := w.wallet.GetPrivateKey(j)
+		if err != nil {
+			return nil, err
+		}
+		return &common.LocalAccount{Name: dn, PrivKey: pk, PubKey: smWallet.PublicKey(pk)}, nil
+	}
+	return nil, fmt.Errorf("account %s is not in this wallet", address.String())
+}
+
+// Accounts returns all the accounts of the open wallet
+func (w *WalletBackend) Accounts() ([]*common.LocalAccount, error) {
+	numberOfAccounts, err := w.wallet.GetNumberOfAccounts()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]*common.LocalAccount, 0, numberOfAccounts)
+	for j := 0; j < numberOfAccounts; j++ {
+		addr, err := w.wallet.GetAddress(j)
+		if err != nil {
+			return nil, err
+		}
+		acc, err := w.AccountByAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, nil
+}
+
 func (w *WalletBackend) ListAccounts() (res []string, err error) {
 	numberOfAccounts, err := w.wallet.GetNumberOfAccounts()
 	if err != nil {
@@ -14,7 +14,7 @@ func TestPublic(t *testing.T) {
 }
 
 func testClient(t *testing.T, server string, secure bool) {
-	client := newGRPCClient(server, secure)
+	client := newGRPCClient(ConnectionOptions{Servers: []string{server}, Secure: secure})
 
 	err := client.Connect()
 	if err != nil {
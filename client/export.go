@@ -0,0 +1,215 @@
+package client
+
+import (
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// addressLabels returns the names of the wallet accounts and the nicknames of the wallet contacts by address
+func (w *WalletBackend) addressLabels(accounts []*common.LocalAccount) (map[string]string, error) {
+	contacts, err := w.wallet.GetContacts()
+	if err != nil {
+		return nil, err
+	}
+	// contacts may be saved in any case and without 0x, e.g. by smapp
+	labels := make(map[string]string, len(contacts))
+	for address, nickname := range contacts {
+		if addr, err := common.ParseAddress(strings.ToLower(address)); err == nil {
+			labels[addr.String()] = nickname
+		}
+	}
+	for _, acc := range accounts {
+		labels[acc.Address().String()] = acc.Name
+	}
+	return labels, nil
+}
+
+// ExportTransactions returns the mesh transactions of all the wallet accounts from fromLayer to toLayer, in layer order.
+// A transaction between two wallet accounts is listed once for each of them.
+func (w *WalletBackend) ExportTransactions(fromLayer uint32, toLayer uint32) ([]common.TxRecord, error) {
+	info, err := w.GetMeshInfo()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := w.Accounts()
+	if err != nil {
+		return nil, err
+	}
+	labels, err := w.addressLabels(accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]common.TxRecord, 0)
+	receipts := make(map[string]*pb.TransactionReceipt)
+	unknownLayers := make(map[string]bool)
+	for _, acc := range accounts {
+		txs, err := w.accountTransactionsInRange(acc.Address(), fromLayer, toLayer, info.CurrentLayer)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.accountReceipts(acc.Address(), receipts); err != nil {
+			return nil, err
+		}
+		for _, e := range common.TxHistory(acc.Address(), txs, 0, nil) {
+			r := common.TxRecord{
+				Account:           acc.Address().String(),
+				AccountName:       acc.Name,
+				Id:                hex.EncodeToString(e.Tx.Id.GetId()),
+				Direction:         e.Direction,
+				Counterparty:      e.Counterparty.String(),
+				CounterpartyLabel: labels[e.Counterparty.String()],
+				Amount:            e.Amount,
+				Fee:               e.Fee,
+			}
+			if receipt, ok := receipts[string(e.Tx.Id.GetId())]; ok {
+				r.Layer = receipt.Layer.GetNumber()
+				r.Fee = receipt.Fee.GetValue()
+				r.Result = common.TxResultText(receipt.Result)
+			} else {
+				unknownLayers[string(e.Tx.Id.GetId())] = true
+			}
+			records = append(records, r)
+		}
+	}
+
+	// without a receipt the layer of a transaction is found by looking through the layers in the range
+	if len(unknownLayers) > 0 {
+		layers, err := w.TransactionLayers(unknownLayers, fromLayer, toLayer)
+		if err != nil {
+			return nil, err
+		}
+		for i := range records {
+			id, _ := hex.DecodeString(records[i].Id)
+			if l, ok := layers[string(id)]; ok {
+				records[i].Layer = l
+			}
+		}
+	}
+	for i := range records {
+		records[i].Time = common.LayerTime(info.GenesisTime, info.LayerDuration, records[i].Layer)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Layer < records[j].Layer })
+	return records, nil
+}
+
+// accountTransactionsInRange returns the mesh transactions of an account from fromLayer to toLayer
+func (w *WalletBackend) accountTransactionsInRange(address gosmtypes.Address, fromLayer, toLayer, currentLayer uint32) ([]*pb.Transaction, error) {
+	txs, _, err := w.AccountMeshTransactions(address, fromLayer, 0, 0)
+	if err != nil || toLayer >= currentLayer {
+		return txs, err
+	}
+	later, _, err := w.AccountMeshTransactions(address, toLayer+1, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	laterIds := make(map[string]bool, len(later))
+	for _, tx := range later {
+		laterIds[string(tx.Id.GetId())] = true
+	}
+	inRange := make([]*pb.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if !laterIds[string(tx.Id.GetId())] {
+			inRange = append(inRange, tx)
+		}
+	}
+	return inRange, nil
+}
+
+// accountReceipts adds the transaction receipts of an account to receipts, keyed by transaction id.
+// Nodes which don't serve receipts add none.
+func (w *WalletBackend) accountReceipts(address gosmtypes.Address, receipts map[string]*pb.TransactionReceipt) error {
+	res, _, err := w.AccountTransactionsReceipts(address, 0, 0)
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, r := range res {
+		receipts[string(r.Id.GetId())] = r
+	}
+	return nil
+}
+
+// AccountTransactionLayers returns the layers of the mesh transactions txs of an account, keyed by transaction id.
+// They come from the receipts of the account, or else from the layers of the mesh. Transactions which
+// weren't found have no layer.
+func (w *WalletBackend) AccountTransactionLayers(address gosmtypes.Address, txs []*pb.Transaction) (map[string]uint32, error) {
+	receipts := make(map[string]*pb.TransactionReceipt)
+	if err := w.accountReceipts(address, receipts); err != nil {
+		return nil, err
+	}
+	layers := make(map[string]uint32, len(txs))
+	unknown := make(map[string]bool)
+	for _, tx := range txs {
+		id := string(tx.Id.GetId())
+		if receipt, ok := receipts[id]; ok {
+			layers[id] = receipt.Layer.GetNumber()
+		} else {
+			unknown[id] = true
+		}
+	}
+	if len(unknown) == 0 {
+		return layers, nil
+	}
+	info, err := w.GetMeshInfo()
+	if err != nil {
+		return nil, err
+	}
+	found, err := w.TransactionLayers(unknown, 0, info.CurrentLayer)
+	if err != nil {
+		return nil, err
+	}
+	for id, layer := range found {
+		layers[id] = layer
+	}
+	return layers, nil
+}
+
+// ExportRewards returns the rewards paid to all the wallet accounts from fromLayer to toLayer, in layer order
+func (w *WalletBackend) ExportRewards(fromLayer uint32, toLayer uint32) ([]common.RewardRecord, error) {
+	info, err := w.GetMeshInfo()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := w.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]common.RewardRecord, 0)
+	for _, acc := range accounts {
+		rewards, _, err := w.AccountRewards(acc.Address(), 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rewards {
+			layer := r.Layer.GetNumber()
+			if layer < fromLayer || layer > toLayer {
+				continue
+			}
+			rec := common.RewardRecord{
+				Account:     acc.Address().String(),
+				AccountName: acc.Name,
+				Layer:       layer,
+				Time:        common.LayerTime(info.GenesisTime, info.LayerDuration, layer),
+				Total:       r.Total.GetValue(),
+				LayerReward: r.LayerReward.GetValue(),
+			}
+			if rec.Total > rec.LayerReward {
+				rec.Fees = rec.Total - rec.LayerReward
+			}
+			records = append(records, rec)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Layer < records[j].Layer })
+	return records, nil
+}
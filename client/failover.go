@@ -0,0 +1,420 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/log"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// time given to a server to answer the health-check
+const healthCheckTimeout = 10 * time.Second
+
+var (
+	// number of times a call is retried when none of the servers answers it
+	maxRetries = 3
+	// time before the first retry, doubled after each retry
+	retryBackoff = 500 * time.Millisecond
+)
+
+// methods which must not be sent twice: they are only retried on another server when they
+// didn't reach the first one
+var nonIdempotentMethods = map[string]bool{
+	"/spacemesh.v1.TransactionService/SubmitTransaction": true,
+}
+
+// endpoint is the connection to one of the API servers. Its fields are guarded by the mutex of the client
+// once the endpoint is in use.
+type endpoint struct {
+	server  string
+	conn    *grpc.ClientConn // nil when the server couldn't be dialed
+	checked bool             // true once health-checked
+	healthy bool             // true if the server answered the health-check
+	synced  bool             // true if the node of the server is synced
+	err     error            // why the server is not healthy
+	proxy   *url.URL         // proxy to the server, or nil
+	closed  bool             // true once the client stopped using the endpoint
+
+	tlsMu    sync.Mutex
+	tlsState *tls.ConnectionState // state of the last TLS handshake with the server
+}
+
+func (e *endpoint) setTLSState(state tls.ConnectionState) {
+	e.tlsMu.Lock()
+	defer e.tlsMu.Unlock()
+	e.tlsState = &state
+}
+
+func (e *endpoint) getTLSState() *tls.ConnectionState {
+	e.tlsMu.Lock()
+	defer e.tlsMu.Unlock()
+	return e.tlsState
+}
+
+func (e *endpoint) state() string {
+	switch {
+	case !e.checked:
+		return "not checked"
+	case !e.healthy:
+		return "down"
+	case !e.synced:
+		return "not synced"
+	}
+	return "synced"
+}
+
+// check health-checks a server with an echo request and its sync status
+func check(ctx context.Context, conn *grpc.ClientConn) (healthy, synced bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	s := apitypes.NewNodeServiceClient(conn)
+	const msg = "health-check"
+	resp, err := s.Echo(ctx, &apitypes.EchoRequest{Msg: &apitypes.SimpleString{Value: msg}})
+	if err != nil {
+		return false, false, err
+	}
+	if resp.Msg.GetValue() != msg {
+		return false, false, status.Error(codes.Unavailable, "unexpected node service echo response")
+	}
+	if st, err := s.Status(ctx, &apitypes.StatusRequest{}); err == nil {
+		synced = st.Status.GetIsSynced()
+	}
+	return true, synced, nil
+}
+
+// checkEndpoints health-checks endpoints concurrently, without holding c.mu so that the other calls go on,
+// and records the results. Endpoints which couldn't be dialed are dialed again with cfg.
+func (c *gRPCClient) checkEndpoints(ctx context.Context, cfg dialConfig, endpoints []*endpoint) {
+	type result struct {
+		conn            *grpc.ClientConn // new connection of an endpoint which couldn't be dialed
+		proxy           *url.URL
+		healthy, synced bool
+		err             error
+	}
+	c.mu.Lock()
+	conns := make([]*grpc.ClientConn, len(endpoints))
+	for i, e := range endpoints {
+		conns[i] = e.conn
+	}
+	c.mu.Unlock()
+
+	results := make([]result, len(endpoints))
+	var wg sync.WaitGroup
+	for i := range endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := &results[i]
+			if conns[i] == nil {
+				if r.conn, r.proxy, r.err = cfg.connectEndpoint(ctx, endpoints[i]); r.err != nil {
+					return
+				}
+				conns[i] = r.conn
+			}
+			r.healthy, r.synced, r.err = check(ctx, conns[i])
+		}(i)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range endpoints {
+		r := results[i]
+		if r.conn != nil {
+			if e.conn != nil || e.closed {
+				// dialed meanwhile by another call, or no longer used
+				r.conn.Close()
+			} else {
+				e.conn, e.proxy = r.conn, r.proxy
+			}
+		}
+		e.checked, e.healthy, e.synced, e.err = true, r.healthy, r.synced, r.err
+	}
+}
+
+// pick returns the index of the endpoint calls should go to: the first healthy and synced one, or else
+// the first healthy one. Endpoints in skip are not picked. It returns -1 if no endpoint is healthy.
+func pick(endpoints []*endpoint, skip map[*endpoint]bool) int {
+	healthy := -1
+	for i, e := range endpoints {
+		if skip[e] || !e.healthy {
+			continue
+		}
+		if e.synced {
+			return i
+		}
+		if healthy < 0 {
+			healthy = i
+		}
+	}
+	return healthy
+}
+
+// failover marks the endpoint failed as down, health-checks the endpoints which were not tried yet
+// within ctx and switches to the best of them. It returns nil if there is none.
+func (c *gRPCClient) failover(ctx context.Context, failed *endpoint, err error, tried map[*endpoint]bool) *endpoint {
+	c.mu.Lock()
+	failed.checked, failed.healthy, failed.synced, failed.err = true, false, false, err
+	if len(c.endpoints) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	if cur := c.endpoints[c.current]; cur != failed && !tried[cur] {
+		// another call already failed over
+		c.mu.Unlock()
+		return cur
+	}
+	var others []*endpoint
+	for _, e := range c.endpoints {
+		if !tried[e] {
+			others = append(others, e)
+		}
+	}
+	cfg := c.dialConfig()
+	c.mu.Unlock()
+	if len(others) == 0 {
+		return nil
+	}
+	c.checkEndpoints(ctx, cfg, others)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.endpoints) == 0 {
+		return nil
+	}
+	if cur := c.endpoints[c.current]; cur != failed && !tried[cur] && cur.healthy {
+		// another call failed over during the health-checks
+		return cur
+	}
+	i := pick(c.endpoints, tried)
+	if i < 0 {
+		return nil
+	}
+	c.current = i
+	log.Warning("API server %s failed: %v. Switching to %s", failed.server, err, c.endpoints[i].server)
+	return c.endpoints[i]
+}
+
+// endpoint returns the endpoint calls are sent to
+func (c *gRPCClient) endpoint() (*endpoint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.endpoints) == 0 {
+		return nil, status.Error(codes.Unavailable, "not connected to an API server")
+	}
+	return c.endpoints[c.current], nil
+}
+
+// answeredEndpoint returns the endpoint of the server which answered the last call, or the current
+// endpoint. c.mu must be held.
+func (c *gRPCClient) answeredEndpoint() *endpoint {
+	for _, e := range c.endpoints {
+		if e.server == c.answered {
+			return e
+		}
+	}
+	if len(c.endpoints) > 0 {
+		return c.endpoints[c.current]
+	}
+	return nil
+}
+
+// conn returns the connection of an endpoint, or an Unavailable error if its server couldn't be dialed
+func (c *gRPCClient) conn(e *endpoint) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.conn == nil {
+		return nil, status.Errorf(codes.Unavailable, "connection error: %v", e.err)
+	}
+	return e.conn, nil
+}
+
+// setAnswered records that the server of an endpoint answered a call, which also means that it is up
+func (c *gRPCClient) setAnswered(e *endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.answered = e.server
+	if e.checked && !e.healthy {
+		e.healthy, e.err = true, nil
+	}
+}
+
+// isTransportError returns true iff err means that the server couldn't be reached or didn't answer in time,
+// rather than that it refused the call
+func isTransportError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// notSent returns true iff err means that the call failed before it reached the server
+func notSent(err error) bool {
+	return status.Code(err) == codes.Unavailable && strings.Contains(status.Convert(err).Message(), "while dialing")
+}
+
+// callContext returns the context of a call, which is also cancelled when the context set with SetContext is done
+func (c *gRPCClient) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	c.ctxMu.Lock()
+	interrupt := c.ctx
+	c.ctxMu.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	if interrupt != nil {
+		go func() {
+			select {
+			case <-interrupt.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
+
+// invoke sends a call to the server of an endpoint, with the deadline set by SetTimeout
+func (c *gRPCClient) invoke(ctx context.Context, e *endpoint, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	conn, err := c.conn(e)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	timeout := c.timeout
+	c.mu.Unlock()
+	if timeout <= 0 {
+		return conn.Invoke(ctx, method, args, reply, opts...)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return conn.Invoke(callCtx, method, args, reply, opts...)
+}
+
+// retryContext bounds the time a call takes with its retries to maxRetries+1 times the timeout
+// set by SetTimeout, so that servers which don't answer in time are retried too
+func (c *gRPCClient) retryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	c.mu.Lock()
+	timeout := c.timeout
+	c.mu.Unlock()
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout*time.Duration(maxRetries+1))
+}
+
+// Invoke implements grpc.ClientConnInterface for the service clients. Calls go to the current server.
+// When it can't be reached or doesn't answer in time, the call fails over to the other servers. When
+// none of them answers, it is retried with an exponential backoff, within maxRetries+1 times the timeout
+// in total. Calls which must not be sent twice are only retried when they didn't reach the server.
+func (c *gRPCClient) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	ctx, cancelRetries := c.retryContext(ctx)
+	defer cancelRetries()
+	tried := make(map[*endpoint]bool)
+	backoff := retryBackoff
+	e, err := c.endpoint()
+	for retries := 0; err == nil; {
+		err = c.invoke(ctx, e, method, args, reply, opts...)
+		if !isTransportError(err) {
+			c.setAnswered(e)
+			return err
+		}
+		if ctx.Err() != nil {
+			// cancelled, or out of time
+			return err
+		}
+		retry := !nonIdempotentMethods[method] || notSent(err)
+		tried[e] = true
+		if next := c.failover(ctx, e, err, tried); next != nil {
+			if !retry {
+				return err
+			}
+			e, err = next, nil
+			continue
+		}
+		if !retry || retries == maxRetries {
+			return err
+		}
+		// none of the servers answered: wait and try them again
+		retries++
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+		tried = make(map[*endpoint]bool)
+		e, err = c.endpoint()
+	}
+	return err
+}
+
+// stream is a stream whose context is cancelled once the stream ended
+type stream struct {
+	grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (s *stream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
+
+// NewStream implements grpc.ClientConnInterface for the service clients. Streams fail over
+// when they can't be opened, not once they are open. Like calls, they end when the context
+// set with SetContext is done.
+func (c *gRPCClient) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, cancel := c.callContext(ctx)
+	tried := make(map[*endpoint]bool)
+	e, err := c.endpoint()
+	for err == nil {
+		var s grpc.ClientStream
+		conn, cerr := c.conn(e)
+		if err = cerr; conn != nil {
+			s, err = conn.NewStream(ctx, desc, method, opts...)
+		}
+		if !isTransportError(err) {
+			c.setAnswered(e)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			return &stream{ClientStream: s, cancel: cancel}, nil
+		}
+		tried[e] = true
+		next := c.failover(ctx, e, err, tried)
+		if next == nil || ctx.Err() != nil {
+			break
+		}
+		e, err = next, nil
+	}
+	cancel()
+	return nil, err
+}
+
+// closeEndpoints closes the connections to the servers. c.mu must be held.
+func (c *gRPCClient) closeEndpoints() error {
+	var err error
+	for _, e := range c.endpoints {
+		e.closed = true
+		if e.conn == nil {
+			continue
+		}
+		if cerr := e.conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	c.endpoints = nil
+	return err
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testNode is a node service which answers echo and status requests
+type testNode struct {
+	apitypes.UnimplementedNodeServiceServer
+	synced   bool
+	hang     bool  // echo requests never get an answer
+	answers  int32 // with hang, number of echo requests answered before hanging
+	failures int32 // number of echo requests which fail as unavailable
+	calls    int32 // number of echo requests
+}
+
+func (n *testNode) Echo(ctx context.Context, req *apitypes.EchoRequest) (*apitypes.EchoResponse, error) {
+	atomic.AddInt32(&n.calls, 1)
+	if n.hang && atomic.AddInt32(&n.answers, -1) < 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if atomic.AddInt32(&n.failures, -1) >= 0 {
+		return nil, status.Error(codes.Unavailable, "overloaded")
+	}
+	return &apitypes.EchoResponse{Msg: req.Msg}, nil
+}
+
+func (n *testNode) Status(context.Context, *apitypes.StatusRequest) (*apitypes.StatusResponse, error) {
+	return &apitypes.StatusResponse{Status: &apitypes.NodeStatus{IsSynced: n.synced, ConnectedPeers: 1}}, nil
+}
+
+// StatusStream sends nothing until the client goes away
+func (n *testNode) StatusStream(_ *apitypes.StatusStreamRequest, stream apitypes.NodeService_StatusStreamServer) error {
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// startNode starts an API server with a node service and returns its address
+func startNode(t *testing.T, synced bool) (string, *grpc.Server) {
+	return startServer(t, &testNode{synced: synced}, nil)
+}
+
+// startServer starts an API server with a node service and optionally a transaction service
+func startServer(t *testing.T, node apitypes.NodeServiceServer, txs apitypes.TransactionServiceServer) (string, *grpc.Server) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	apitypes.RegisterNodeServiceServer(s, node)
+	if txs != nil {
+		apitypes.RegisterTransactionServiceServer(s, txs)
+	}
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return l.Addr().String(), s
+}
+
+// deadServer returns the address of a port nothing listens to
+func deadServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return addr
+}
+
+func TestConnectSkipsDownServers(t *testing.T) {
+	dead := deadServer(t)
+	live, _ := startNode(t, true)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{dead, live}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	require.NoError(t, c.Echo())
+	info := c.ServerInfo()
+	assert.True(t, strings.HasPrefix(info, live), info)
+	assert.Contains(t, info, dead+" (down)")
+	assert.Contains(t, info, live+" (synced)")
+}
+
+func TestConnectPrefersSyncedServers(t *testing.T) {
+	notSynced, _ := startNode(t, false)
+	synced, _ := startNode(t, true)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{notSynced, synced}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	status, err := c.NodeStatus()
+	require.NoError(t, err)
+	assert.True(t, status.IsSynced)
+	assert.True(t, strings.HasPrefix(c.ServerInfo(), synced))
+}
+
+func TestCallFailsOver(t *testing.T) {
+	first, s := startNode(t, true)
+	second, _ := startNode(t, true)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{first, second}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	require.NoError(t, c.Echo())
+	assert.True(t, strings.HasPrefix(c.ServerInfo(), first))
+
+	s.Stop()
+	require.NoError(t, c.Echo())
+	info := c.ServerInfo()
+	assert.True(t, strings.HasPrefix(info, second), info)
+	assert.Contains(t, info, first+" (down)")
+}
+
+func TestFailoverHealthChecksWithinTheCall(t *testing.T) {
+	first, s := startNode(t, true)
+	second, _ := startServer(t, &testNode{synced: true, hang: true, answers: 1}, nil)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{first, second}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	require.NoError(t, c.Echo())
+
+	// the health-check of the second server hangs: it ends with the call, and doesn't block the client
+	s.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	info := make(chan time.Duration, 1)
+	time.AfterFunc(100*time.Millisecond, func() {
+		start := time.Now()
+		c.ServerInfo()
+		info <- time.Since(start)
+	})
+	start := time.Now()
+	_, err := apitypes.NewNodeServiceClient(c).Echo(ctx, &apitypes.EchoRequest{Msg: &apitypes.SimpleString{Value: "hi"}})
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	assert.Less(t, int64(<-info), int64(time.Second))
+}
+
+func TestCallFailsWhenAllServersAreDown(t *testing.T) {
+	fastRetries(t)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{deadServer(t), deadServer(t)}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	assert.Error(t, c.Echo())
+}
+
+// fastRetries shortens the retry backoff for the duration of a test
+func fastRetries(t *testing.T) {
+	backoff := retryBackoff
+	retryBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { retryBackoff = backoff })
+}
+
+// testTransactions is a transaction service which is always unavailable
+type testTransactions struct {
+	apitypes.UnimplementedTransactionServiceServer
+	calls int32
+}
+
+func (s *testTransactions) SubmitTransaction(context.Context, *apitypes.SubmitTransactionRequest) (*apitypes.SubmitTransactionResponse, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return nil, status.Error(codes.Unavailable, "overloaded")
+}
+
+func TestCallTimeout(t *testing.T) {
+	fastRetries(t)
+	node := &testNode{hang: true}
+	addr, _ := startServer(t, node, nil)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{addr}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	// a server which doesn't answer in time is retried within maxRetries+1 times the timeout
+	c.SetTimeout(50 * time.Millisecond)
+	start := time.Now()
+	err := c.Echo()
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Greater(t, atomic.LoadInt32(&node.calls), int32(1))
+	assert.LessOrEqual(t, atomic.LoadInt32(&node.calls), int32(maxRetries+1))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestCallRetriesWithBackoff(t *testing.T) {
+	fastRetries(t)
+	node := &testNode{failures: 2}
+	addr, _ := startServer(t, node, nil)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{addr}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	require.NoError(t, c.Echo())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&node.calls))
+}
+
+func TestSubmitIsNotRetried(t *testing.T) {
+	fastRetries(t)
+	txs := &testTransactions{}
+	addr, _ := startServer(t, &testNode{}, txs)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{addr}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	_, err := c.SubmitTransaction([]byte{1})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, err.Error(), "check its state")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&txs.calls))
+}
+
+func TestSetContextCancelsCalls(t *testing.T) {
+	addr, _ := startServer(t, &testNode{hang: true}, nil)
+	c := newGRPCClient(ConnectionOptions{Servers: []string{addr}})
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.SetContext(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err := c.Echo()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+
+	// and so it cancels streams
+	ctx, cancel = context.WithCancel(context.Background())
+	c.SetContext(ctx)
+	stream, err := c.getNodeServiceClient().StatusStream(context.Background(), &apitypes.StatusStreamRequest{})
+	require.NoError(t, err)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	c.SetContext(nil)
+
+	// the calls of a detached client, such as the scheduler's, are not cancelled
+	c.SetTimeout(200 * time.Millisecond)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(c.detached().Echo()))
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"context"
 
 	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
@@ -57,8 +58,8 @@ func (c *gRPCClient) AccountRewards(address gosmtypes.Address, offset uint32, ma
 			AccountDataFlags: uint32(apitypes.AccountDataFlag_ACCOUNT_DATA_FLAG_REWARD),
 		},
 
-		MaxResults: 0,
-		Offset:     0,
+		MaxResults: maxResults,
+		Offset:     offset,
 	})
 
 	if err != nil {
@@ -87,8 +88,8 @@ func (c *gRPCClient) AccountTransactionsReceipts(address gosmtypes.Address, offs
 			AccountDataFlags: uint32(apitypes.AccountDataFlag_ACCOUNT_DATA_FLAG_TRANSACTION_RECEIPT),
 		},
 
-		MaxResults: 0,
-		Offset:     0,
+		MaxResults: maxResults,
+		Offset:     offset,
 	})
 
 	if err != nil {
@@ -108,3 +109,17 @@ func (c *gRPCClient) AccountTransactionsReceipts(address gosmtypes.Address, offs
 	return receipts, resp.TotalResults, nil
 
 }
+
+// TransactionReceipt returns the receipt of a transaction sent from or to an account, or nil if the account has no such receipt
+func (c *gRPCClient) TransactionReceipt(address gosmtypes.Address, txId []byte) (*apitypes.TransactionReceipt, error) {
+	receipts, _, err := c.AccountTransactionsReceipts(address, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range receipts {
+		if bytes.Equal(r.Id.GetId(), txId) {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
@@ -2,9 +2,16 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/fullstorydev/grpcurl"
+	"github.com/spacemeshos/CLIWallet/common"
 	"google.golang.org/grpc/credentials"
 
 	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
@@ -14,10 +21,57 @@ import (
 const DefaultGRPCServer = "localhost:9092"
 const DefaultSecureConnection = false
 
+// Version is the version of the wallet sent in the user agent. Builds set it with
+// -ldflags "-X github.com/spacemeshos/CLIWallet/client.Version=<version>".
+var Version = "dev-build"
+
+// userAgentVersion is Version, or the module version when the build didn't set it
+// (e.g. go install github.com/spacemeshos/CLIWallet@<version>)
+func userAgentVersion() string {
+	if Version != "dev-build" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return Version
+}
+
+// DefaultCallTimeout is the time given to the API server to answer a call
+const DefaultCallTimeout = 30 * time.Second
+
+// ConnectionOptions are the settings of the connection to the API servers
+type ConnectionOptions struct {
+	Servers []string // API servers, in order of preference
+	Secure  bool
+	TLS     TLSOptions // used when Secure is set
+	// file of the public keys pinned for the servers, used when Secure is set. Empty disables pinning.
+	KnownServers string
+	// URL of the SOCKS5 or HTTP proxy to the servers, or ProxyDirect. Empty uses the proxy environment variables.
+	Proxy string
+	Auth  AuthOptions // token sent to the servers with each call
+}
+
+// connection is the state of the connection to the API servers, shared by the clients of a wallet
+type connection struct {
+	connectMu sync.Mutex // serializes the connections, which don't hold mu while dialing
+
+	mu        sync.Mutex
+	options   ConnectionOptions
+	endpoints []*endpoint          // connections to the servers, in the same order
+	current   int                  // index of the endpoint calls are sent to
+	answered  string               // server which answered the last call
+	timeout   time.Duration        // deadline of each call, or 0 for none
+	known     *common.KnownServers // pinned public keys, or nil
+	auth      *tokenSource         // token sent with the calls, or nil
+}
+
 type gRPCClient struct {
-	connection               *grpc.ClientConn
-	server                   string
-	secureConnection         bool
+	*connection
+
+	ctxMu sync.Mutex
+	ctx   context.Context // cancels the calls of this client when done, or nil
+
 	nodeServiceClient        apitypes.NodeServiceClient
 	debugServiceClient       apitypes.DebugServiceClient
 	meshServiceClient        apitypes.MeshServiceClient
@@ -26,61 +80,186 @@ type gRPCClient struct {
 	smesherServiceClient     apitypes.SmesherServiceClient
 }
 
-func newGRPCClient(server string, secureConnection bool) *gRPCClient {
-	return &gRPCClient{
-		nil,
-		server,
-		secureConnection,
-		nil,
-		nil,
-		nil,
-		nil,
-		nil,
-		nil,
-	}
+func newGRPCClient(options ConnectionOptions) *gRPCClient {
+	options.Servers = append([]string{}, options.Servers...)
+	return &gRPCClient{connection: &connection{
+		options: options,
+		timeout: DefaultCallTimeout,
+	}}
+}
+
+// detached returns a client which shares the connection of c, but whose calls are not cancelled by the
+// context set with SetContext, for the calls made in the background
+func (c *gRPCClient) detached() *gRPCClient {
+	return &gRPCClient{connection: c.connection}
 }
 
+// SetTimeout sets the time given to the API server to answer a call. 0 means no deadline.
+func (c *gRPCClient) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
+
+// SetContext sets a context which cancels the calls in flight when it is done, such as when the user
+// presses Ctrl-C. nil removes it. It only cancels the calls made through this client, not those of the
+// detached clients sharing its connection.
+func (c *gRPCClient) SetContext(ctx context.Context) {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	c.ctx = ctx
+}
+
+// dialConfig is what the endpoints of a connection are dialed with
+type dialConfig struct {
+	options ConnectionOptions
+	known   *common.KnownServers // pinned public keys, or nil
+	auth    *tokenSource         // token sent with the calls, or nil
+}
+
+// dialConfig returns what the current endpoints are dialed with. c.mu must be held.
+func (c *gRPCClient) dialConfig() dialConfig {
+	return dialConfig{options: c.options, known: c.known, auth: c.auth}
+}
+
+// connectionOptions returns the options of the current connection
+func (c *gRPCClient) connectionOptions() ConnectionOptions {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.options
+}
+
+// Connect connects to the API servers. With more than one server, they are health-checked and
+// calls go to the first healthy and synced one. It fails only if none of the servers can be dialed.
 func (c *gRPCClient) Connect() error {
-	if c.connection != nil {
-		_ = c.connection.Close()
+	return c.connect(c.connectionOptions())
+}
+
+// Reconnect connects to the API servers with other options. The current connection is kept when the new one can't be used.
+func (c *gRPCClient) Reconnect(options ConnectionOptions) error {
+	options.Servers = append([]string{}, options.Servers...)
+	return c.connect(options)
+}
+
+// connect dials the servers of options and health-checks them without holding c.mu, so that the calls
+// go on with the current connection meanwhile. The new connection replaces the current one once it is up.
+func (c *gRPCClient) connect(options ConnectionOptions) error {
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
+	if len(options.Servers) == 0 {
+		return errors.New("no API server")
 	}
 
-	var conn *grpc.ClientConn
+	cfg := dialConfig{options: options}
+	if options.Secure && options.KnownServers != "" {
+		known, err := common.LoadKnownServers(options.KnownServers)
+		if err != nil {
+			return err
+		}
+		cfg.known = known
+	}
+	if options.Auth.IsSet() {
+		cfg.auth = newTokenSource(options.Auth)
+	}
+
+	ctx := context.Background()
+	endpoints := make([]*endpoint, len(options.Servers))
+	var wg sync.WaitGroup
+	for i, server := range options.Servers {
+		endpoints[i] = &endpoint{server: server}
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			e.conn, e.proxy, e.err = cfg.connectEndpoint(ctx, e)
+		}(endpoints[i])
+	}
+	wg.Wait()
+
 	var err error
-	if !c.secureConnection {
+	dialed := false
+	for _, e := range endpoints {
+		dialed = dialed || e.conn != nil
+		if err == nil {
+			err = e.err
+		}
+	}
+	if !dialed {
+		c.mu.Lock()
+		if len(c.endpoints) == 0 {
+			// there is no connection to keep: the pinned keys can still be changed to fix the failure
+			c.options, c.known, c.auth = options, cfg.known, cfg.auth
+		}
+		c.mu.Unlock()
+		return err
+	}
+	current := 0
+	if len(endpoints) > 1 {
+		c.checkEndpoints(ctx, cfg, endpoints)
+		c.mu.Lock()
+		if i := pick(endpoints, nil); i >= 0 {
+			current = i
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeEndpoints()
+	c.options, c.known, c.auth = options, cfg.known, cfg.auth
+	c.endpoints, c.current, c.answered = endpoints, current, ""
+	return nil
+}
+
+// connectEndpoint dials the server of an endpoint, through the proxy of the options. It returns the
+// connection and the proxy it goes through.
+func (cfg dialConfig) connectEndpoint(ctx context.Context, e *endpoint) (*grpc.ClientConn, *url.URL, error) {
+	proxyURL, err := proxyFor(cfg.options.Proxy, e.server)
+	if err != nil {
+		return nil, nil, err
+	}
+	dial, err := proxyDialer(proxyURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := []grpc.DialOption{grpc.WithUserAgent("sm-cli-wallet/" + userAgentVersion())}
+	if cfg.auth != nil {
+		opts = append(opts, grpc.WithUnaryInterceptor(cfg.auth.unaryInterceptor), grpc.WithStreamInterceptor(cfg.auth.streamInterceptor))
+	}
+	var conn *grpc.ClientConn
+	if !cfg.options.Secure {
 		// simple grpc dial
-		conn, err = grpc.Dial(c.server, grpc.WithInsecure())
+		opts = append(opts, grpc.WithInsecure(), grpc.WithContextDialer(dial))
+		conn, err = grpc.Dial(e.server, opts...)
 	} else {
-		// secure connection without client cert or server cert validation
-		conn, err = c.dial(c.server)
+		conn, err = cfg.dial(ctx, e, dial, opts...)
 	}
-
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	c.connection = conn
-	return nil
+	return conn, proxyURL, nil
 }
 
-// used in secure dial (tls)
-func (c *gRPCClient) dial(address string) (*grpc.ClientConn, error) {
+// dial connects to the server of an endpoint with TLS through dial, adding opts to the dial options.
+// The server certificate is verified with the CA bundle of the TLS options, or the system CAs.
+func (cfg dialConfig) dial(ctx context.Context, e *endpoint, dial dialFunc, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 
 	dialTime := 60 * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), dialTime)
+	ctx, cancel := context.WithTimeout(ctx, dialTime)
 	defer cancel()
 
-	var creds credentials.TransportCredentials
-	var err error
-	creds, err = grpcurl.ClientTransportCredentials(false, "", "", "")
+	conf, err := cfg.options.TLS.Config()
 	if err != nil {
 		return nil, err
 	}
+	if cfg.known != nil {
+		known := cfg.known
+		conf.VerifyConnection = func(state tls.ConnectionState) error {
+			return checkPin(known, e.server, state)
+		}
+	}
+	creds := &handshakeCredentials{TransportCredentials: credentials.NewTLS(conf), handshake: e.setTLSState}
 
-	// todo: set release version in user agent
-	var opts []grpc.DialOption
-	opts = append(opts, grpc.WithUserAgent("sm-cli-wallet/dev-build"))
-
-	cc, err := grpcurl.BlockingDial(ctx, "tcp", address, creds, opts...)
+	cc, err := blockingDial(ctx, e.server, creds, dial, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -89,20 +268,41 @@ func (c *gRPCClient) dial(address string) (*grpc.ClientConn, error) {
 }
 
 func (c *gRPCClient) Close() error {
-	if c.connection != nil {
-		return c.connection.Close()
-	}
-	return nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeEndpoints()
 }
 
+// ServerInfo describes the server which answered the last call and, when there are several servers, their health
 func (c *gRPCClient) ServerInfo() string {
-	s := c.server + " (GRPC API 2.0)"
-	if c.secureConnection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	server := c.answered
+	if server == "" && len(c.endpoints) > 0 {
+		server = c.endpoints[c.current].server
+	}
+	e := c.answeredEndpoint()
+	s := server + " (GRPC API 2.0)"
+	if c.options.Secure {
 		s += ". Secure Connection."
+		if e != nil {
+			if state := e.getTLSState(); state != nil {
+				s += " " + describeTLS(state) + "."
+			}
+		}
 	} else {
 		s += ". >> Insecure Connection. Use only with a local trusted server <<"
 	}
-
+	if e != nil && e.proxy != nil {
+		s += " Through proxy " + proxyName(e.proxy) + "."
+	}
+	if len(c.endpoints) > 1 {
+		states := make([]string, len(c.endpoints))
+		for i, e := range c.endpoints {
+			states[i] = fmt.Sprintf("%s (%s)", e.server, e.state())
+		}
+		s += " Servers: " + strings.Join(states, ", ")
+	}
 	return s
 }
 
@@ -110,28 +310,28 @@ func (c *gRPCClient) ServerInfo() string {
 
 func (c *gRPCClient) getNodeServiceClient() apitypes.NodeServiceClient {
 	if c.nodeServiceClient == nil {
-		c.nodeServiceClient = apitypes.NewNodeServiceClient(c.connection)
+		c.nodeServiceClient = apitypes.NewNodeServiceClient(c)
 	}
 	return c.nodeServiceClient
 }
 
 func (c *gRPCClient) getDebugServiceClient() apitypes.DebugServiceClient {
 	if c.debugServiceClient == nil {
-		c.debugServiceClient = apitypes.NewDebugServiceClient(c.connection)
+		c.debugServiceClient = apitypes.NewDebugServiceClient(c)
 	}
 	return c.debugServiceClient
 }
 
 func (c *gRPCClient) getMeshServiceClient() apitypes.MeshServiceClient {
 	if c.meshServiceClient == nil {
-		c.meshServiceClient = apitypes.NewMeshServiceClient(c.connection)
+		c.meshServiceClient = apitypes.NewMeshServiceClient(c)
 	}
 	return c.meshServiceClient
 }
 
 func (c *gRPCClient) getGlobalStateServiceClient() apitypes.GlobalStateServiceClient {
 	if c.globalStateServiceClient == nil {
-		c.globalStateServiceClient = apitypes.NewGlobalStateServiceClient(c.connection)
+		c.globalStateServiceClient = apitypes.NewGlobalStateServiceClient(c)
 	}
 	return c.globalStateServiceClient
 
@@ -139,14 +339,14 @@ func (c *gRPCClient) getGlobalStateServiceClient() apitypes.GlobalStateServiceCl
 
 func (c *gRPCClient) getTransactionServiceClient() apitypes.TransactionServiceClient {
 	if c.transactionServiceClient == nil {
-		c.transactionServiceClient = apitypes.NewTransactionServiceClient(c.connection)
+		c.transactionServiceClient = apitypes.NewTransactionServiceClient(c)
 	}
 	return c.transactionServiceClient
 }
 
 func (c *gRPCClient) getSmesherServiceClient() apitypes.SmesherServiceClient {
 	if c.smesherServiceClient == nil {
-		c.smesherServiceClient = apitypes.NewSmesherServiceClient(c.connection)
+		c.smesherServiceClient = apitypes.NewSmesherServiceClient(c)
 	}
 	return c.smesherServiceClient
 }
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"context"
 
 	"github.com/spacemeshos/CLIWallet/common"
@@ -11,6 +12,13 @@ import (
 
 // GetMeshTransactions returns the transactions on the mesh to or from an address.
 func (c *gRPCClient) GetMeshTransactions(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.Transaction, uint32, error) {
+	return c.AccountMeshTransactions(address, 0, offset, maxResults)
+}
+
+// AccountMeshTransactions returns the transactions on the mesh to or from an address starting at minLayer,
+// in layer order. The returned total is the number of results reported by the node, which counts a
+// transaction once for every block it is included in.
+func (c *gRPCClient) AccountMeshTransactions(address gosmtypes.Address, minLayer uint32, offset uint32, maxResults uint32) ([]*apitypes.Transaction, uint32, error) {
 	ms := c.getMeshServiceClient()
 
 	resp, err := ms.AccountMeshDataQuery(context.Background(), &apitypes.AccountMeshDataQueryRequest{
@@ -18,7 +26,7 @@ func (c *gRPCClient) GetMeshTransactions(address gosmtypes.Address, offset uint3
 			AccountId:            &apitypes.AccountId{Address: address.Bytes()},
 			AccountMeshDataFlags: uint32(apitypes.AccountMeshDataFlag_ACCOUNT_MESH_DATA_FLAG_TRANSACTIONS),
 		},
-		MinLayer:   &apitypes.LayerNumber{Number: 0},
+		MinLayer:   &apitypes.LayerNumber{Number: minLayer},
 		MaxResults: maxResults,
 		Offset:     offset,
 	})
@@ -27,6 +35,7 @@ func (c *gRPCClient) GetMeshTransactions(address gosmtypes.Address, offset uint3
 		return nil, 0, err
 	}
 
+	// a transaction included in more than one block is returned once per block
 	txsMap := make(map[string]bool)
 	txs := make([]*apitypes.Transaction, 0)
 
@@ -40,11 +49,7 @@ func (c *gRPCClient) GetMeshTransactions(address gosmtypes.Address, offset uint3
 			}
 		}
 	}
-	// hack alert: for now, we return the number of filtered results and not the results returned from the api
-	// because they include duplicated transactions in case where a transaction is on more than 1 mesh block
-
-	// todo: think about default sorting
-	return txs, uint32(len(txs)), nil
+	return txs, resp.TotalResults, nil
 }
 
 // GetMeshActivations returns activations where the address is the coinbase
@@ -127,3 +132,107 @@ func (c *gRPCClient) GetMeshInfo() (*common.NetInfo, error) {
 
 	return netInfo, nil
 }
+
+// GetLayers returns the layers from startLayer to endLayer, including their blocks and transactions
+func (c *gRPCClient) GetLayers(startLayer uint32, endLayer uint32) ([]*apitypes.Layer, error) {
+	ms := c.getMeshServiceClient()
+
+	resp, err := ms.LayersQuery(context.Background(), &apitypes.LayersQueryRequest{
+		StartLayer: &apitypes.LayerNumber{Number: startLayer},
+		EndLayer:   &apitypes.LayerNumber{Number: endLayer},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Layer, nil
+}
+
+// number of recent layers looked at when estimating fees
+const feeSampleLayers = 20
+
+// EstimateFees suggests transaction fees based on the fees offered by the transactions in recent layers
+func (c *gRPCClient) EstimateFees() (*common.FeeEstimate, error) {
+	ms := c.getMeshServiceClient()
+
+	currLayer, err := ms.CurrentLayer(context.Background(), &apitypes.CurrentLayerRequest{})
+	if err != nil {
+		return nil, err
+	}
+	endLayer := currLayer.Layernum.Number
+	startLayer := uint32(0)
+	if endLayer > feeSampleLayers {
+		startLayer = endLayer - feeSampleLayers
+	}
+
+	layers, err := c.GetLayers(startLayer, endLayer)
+	if err != nil {
+		return nil, err
+	}
+
+	fees := make([]uint64, 0)
+	for _, l := range layers {
+		for _, b := range l.Blocks {
+			for _, tx := range b.Transactions {
+				if tx.GasOffered != nil {
+					fees = append(fees, tx.GasOffered.GasPrice)
+				}
+			}
+		}
+	}
+
+	estimate := common.EstimateFees(fees)
+	return &estimate, nil
+}
+
+// FindTransactionLayer returns the number of the first layer from startLayer to endLayer with a block
+// which includes the transaction. It returns false if the transaction is not in these layers.
+func (c *gRPCClient) FindTransactionLayer(txId []byte, startLayer uint32, endLayer uint32) (uint32, bool, error) {
+	layers, err := c.GetLayers(startLayer, endLayer)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, l := range layers {
+		for _, b := range l.Blocks {
+			for _, tx := range b.Transactions {
+				if tx.Id != nil && bytes.Equal(tx.Id.Id, txId) {
+					return l.Number.Number, true, nil
+				}
+			}
+		}
+	}
+	return 0, false, nil
+}
+
+// number of layers requested at once when looking for the layers of transactions
+const txLayersBatch = 100
+
+// TransactionLayers returns the layers from startLayer to endLayer which include the transactions with the given ids,
+// keyed by transaction id. Transactions which are not in these layers are left out.
+func (c *gRPCClient) TransactionLayers(txIds map[string]bool, startLayer uint32, endLayer uint32) (map[string]uint32, error) {
+	layers := make(map[string]uint32)
+	for start := startLayer; start <= endLayer && len(layers) < len(txIds); start += txLayersBatch {
+		end := start + txLayersBatch - 1
+		if end > endLayer || end < start {
+			end = endLayer
+		}
+		res, err := c.GetLayers(start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range res {
+			for _, b := range l.Blocks {
+				for _, tx := range b.Transactions {
+					id := string(tx.Id.GetId())
+					if _, ok := layers[id]; !ok && txIds[id] {
+						layers[id] = l.Number.GetNumber()
+					}
+				}
+			}
+		}
+		if end == endLayer {
+			break
+		}
+	}
+	return layers, nil
+}
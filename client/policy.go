@@ -0,0 +1,87 @@
+package client
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// transferApproval allows one transfer which needs the second password of a spending policy
+type transferApproval struct {
+	sender    gosmtypes.Address
+	recipient gosmtypes.Address
+	amount    uint64
+}
+
+// SpendingPolicy returns the spending policy of an account, or nil if it has none
+func (w *WalletBackend) SpendingPolicy(address gosmtypes.Address) (*common.SpendingPolicy, error) {
+	return w.wallet.GetPolicy(address.String())
+}
+
+// SetSpendingPolicy stores the spending policy of an account in the wallet
+func (w *WalletBackend) SetSpendingPolicy(p common.SpendingPolicy) error {
+	return w.wallet.SetPolicy(p)
+}
+
+// RemoveSpendingPolicy deletes the spending policy of an account from the wallet
+func (w *WalletBackend) RemoveSpendingPolicy(address gosmtypes.Address) error {
+	return w.wallet.RemovePolicy(address.String())
+}
+
+// SpentToday returns the amount sent by an account since local midnight, as recorded in the wallet
+func (w *WalletBackend) SpentToday(address gosmtypes.Address) (uint64, error) {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return w.wallet.SpentSince(address.String(), midnight)
+}
+
+// recordSpend adds a transfer, amount and fee, to the amounts sent by an account with a spending policy.
+// The records are kept in the encrypted wallet, so that they can't be removed to reset the daily limit.
+func (w *WalletBackend) recordSpend(sender gosmtypes.Address, amount uint64) error {
+	p, err := w.SpendingPolicy(sender)
+	if err != nil || p == nil {
+		return err
+	}
+	return w.wallet.AddSpend(sender.String(), common.SpendRecord{Time: time.Now(), Amount: amount})
+}
+
+// ApproveTransfer checks the second password of the spending policy of sender and
+// allows the next transfer of amount to recipient
+func (w *WalletBackend) ApproveTransfer(sender, recipient gosmtypes.Address, amount uint64, password string) error {
+	p, err := w.SpendingPolicy(sender)
+	if err != nil {
+		return err
+	}
+	if p == nil || !p.CheckApprovalPassword(password) {
+		return errors.New("wrong second password")
+	}
+	w.approvalMu.Lock()
+	w.approval = &transferApproval{sender: sender, recipient: recipient, amount: amount}
+	w.approvalMu.Unlock()
+	return nil
+}
+
+// checkSpendingPolicy returns a *common.PolicyViolation if the transfer breaks the spending policy of sender.
+// A matching approval is used up by the check.
+func (w *WalletBackend) checkSpendingPolicy(sender, recipient gosmtypes.Address, amount, fee uint64) error {
+	p, err := w.SpendingPolicy(sender)
+	if err != nil || p == nil {
+		return err
+	}
+	spent, err := w.SpentToday(sender)
+	if err != nil {
+		return err
+	}
+
+	w.approvalMu.Lock()
+	a := w.approval
+	approved := a != nil && a.sender == sender && a.recipient == recipient && a.amount == amount
+	if approved {
+		w.approval = nil
+	}
+	w.approvalMu.Unlock()
+
+	return p.Check(recipient, amount, fee, spent, time.Now(), approved)
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// PreflightTransfer checks a transfer against the projected state of the sender before it is submitted
+func (w *WalletBackend) PreflightTransfer(sender, recipient gosmtypes.Address, amount, fee, nonce uint64) (*common.PreflightResult, error) {
+	p, err := w.senderPreflight(w.gRPCClient, sender, amount, fee, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.AccountByAddress(recipient); err == nil {
+		p.RecipientIsOwn = true
+	}
+
+	recipientState, err := w.AccountState(recipient)
+	if err != nil {
+		return nil, err
+	}
+	p.RecipientSeen = recipientState.StateProjected.GetBalance().GetValue() > 0 || recipientState.StateProjected.GetCounter() > 0
+	if !p.RecipientSeen {
+		_, total, err := w.GetMeshTransactions(recipient, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		p.RecipientSeen = total > 0
+	}
+
+	res := p.Check()
+	return &res, nil
+}
+
+// PreflightFunds checks that the sender can pay for transfers of amount plus fee in total, with nonces
+// starting at nonce, such as a batch or a sweep. The recipients are not checked.
+func (w *WalletBackend) PreflightFunds(sender gosmtypes.Address, amount, fee, nonce uint64) (*common.PreflightResult, error) {
+	return w.preflightFunds(w.gRPCClient, sender, amount, fee, nonce)
+}
+
+func (w *WalletBackend) preflightFunds(api *gRPCClient, sender gosmtypes.Address, amount, fee, nonce uint64) (*common.PreflightResult, error) {
+	p, err := w.senderPreflight(api, sender, amount, fee, nonce)
+	if err != nil {
+		return nil, err
+	}
+	p.RecipientSeen = true
+	res := p.Check()
+	return &res, nil
+}
+
+// senderPreflight returns what is known about the sender of a transfer
+func (w *WalletBackend) senderPreflight(api *gRPCClient, sender gosmtypes.Address, amount, fee, nonce uint64) (*common.TransferPreflight, error) {
+	p := &common.TransferPreflight{Amount: amount, Fee: fee, Nonce: nonce}
+
+	state, err := api.AccountState(sender)
+	if err != nil {
+		return nil, err
+	}
+	p.ProjectedBalance = state.StateProjected.GetBalance().GetValue()
+	p.ProjectedNonce = state.StateProjected.GetCounter()
+
+	if p.Pending, err = w.PendingTransactions(sender); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// insufficientFunds returns the error of a preflight which found that the transfer can't be paid for, or nil
+func insufficientFunds(res *common.PreflightResult) error {
+	if !res.Insufficient {
+		return nil
+	}
+	// the first warning tells why
+	return errors.New(res.Warnings[0])
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+)
+
+// ProxyDirect is the proxy setting which connects to the API servers directly, ignoring the proxy environment variables
+const ProxyDirect = "direct"
+
+// dialFunc opens a connection to an address
+type dialFunc func(ctx context.Context, address string) (net.Conn, error)
+
+// ParseProxy parses the URL of a SOCKS5 or HTTP proxy, such as socks5://127.0.0.1:9050
+func ParseProxy(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %s. Use socks5://host:port, socks5h://host:port or http://host:port", proxyURL)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+		return u, nil
+	}
+	return nil, fmt.Errorf("unsupported proxy %s. Use a socks5, socks5h or http proxy", proxyURL)
+}
+
+// proxyFor returns the proxy used to connect to server, or nil to connect directly. An empty
+// setting uses the HTTPS_PROXY, ALL_PROXY and NO_PROXY environment variables.
+func proxyFor(setting, server string) (*url.URL, error) {
+	switch setting {
+	case ProxyDirect:
+		return nil, nil
+	case "":
+		return proxyFromEnvironment(server)
+	}
+	return ParseProxy(setting)
+}
+
+// proxyFromEnvironment returns the proxy set in the environment for server, as curl does: HTTPS_PROXY,
+// then ALL_PROXY, unless the server is listed in NO_PROXY. Servers on the loopback interface are never proxied.
+func proxyFromEnvironment(server string) (*url.URL, error) {
+	setting := getenv("HTTPS_PROXY", "https_proxy")
+	if setting == "" {
+		setting = getenv("ALL_PROXY", "all_proxy")
+	}
+	if setting == "" {
+		return nil, nil
+	}
+	// httpproxy knows which servers NO_PROXY excludes, but not the socks5h scheme, so it only gets a placeholder proxy
+	env := httpproxy.Config{HTTPSProxy: "http://proxy", NoProxy: getenv("NO_PROXY", "no_proxy")}
+	u, err := env.ProxyFunc()(&url.URL{Scheme: "https", Host: server})
+	if err != nil || u == nil {
+		return nil, err
+	}
+	if !strings.Contains(setting, "://") {
+		// a proxy without scheme is an HTTP proxy
+		setting = "http://" + setting
+	}
+	return ParseProxy(setting)
+}
+
+func getenv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// proxyName is the proxy without its credentials, for display
+func proxyName(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// proxyDialer returns a dialer which connects through a proxy, or directly when proxyURL is nil
+func proxyDialer(proxyURL *url.URL) (dialFunc, error) {
+	direct := &net.Dialer{}
+	if proxyURL == nil {
+		return func(ctx context.Context, address string) (net.Conn, error) {
+			return direct.DialContext(ctx, "tcp", address)
+		}, nil
+	}
+	if proxyURL.Scheme == "http" {
+		return func(ctx context.Context, address string) (net.Conn, error) {
+			return httpConnect(ctx, direct, proxyURL, address)
+		}, nil
+	}
+	// the SOCKS5 dialer sends host names to the proxy, which resolves them: that's what tor needs
+	d, err := proxy.FromURL(proxyURL, direct)
+	if err != nil {
+		return nil, err
+	}
+	socks := d.(proxy.ContextDialer)
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		return socks.DialContext(ctx, "tcp", address)
+	}, nil
+}
+
+// httpConnect opens a tunnel to address through an HTTP proxy with the CONNECT method
+func httpConnect(ctx context.Context, direct *net.Dialer, proxyURL *url.URL, address string) (net.Conn, error) {
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), "80")
+	}
+	conn, err := direct.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: address}, Host: address, Header: make(http.Header)}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send the request to proxy %s: %v", proxyName(proxyURL), err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read the answer of proxy %s: %v", proxyName(proxyURL), err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyName(proxyURL), address, resp.Status)
+	}
+	if r.Buffered() > 0 {
+		// the server spoke first, and the reader got some of its bytes
+		return &bufferedConn{conn, r}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were already read into a buffer
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// blockingDial connects to a server with TLS through dial and waits until the connection is up. grpc only
+// reports the failures of the connection or of the TLS handshake when the dial times out, so they are
+// caught on the way to fail fast with the actual error.
+func blockingDial(ctx context.Context, address string, creds *handshakeCredentials, dial dialFunc, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	result := make(chan interface{}, 1)
+	writeResult := func(res interface{}) {
+		// only the first result is needed
+		select {
+		case result <- res:
+		default:
+		}
+	}
+
+	failing := *creds
+	failing.failed = func(err error) { writeResult(err) }
+	dialer := func(ctx context.Context, address string) (net.Conn, error) {
+		conn, err := dial(ctx, address)
+		if err != nil {
+			writeResult(err)
+		}
+		return conn, err
+	}
+	opts = append(opts,
+		grpc.WithBlock(),
+		grpc.FailOnNonTempDialError(true),
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(&failing),
+	)
+	go func() {
+		conn, err := grpc.DialContext(ctx, address, opts...)
+		if err != nil {
+			writeResult(err)
+		} else {
+			writeResult(conn)
+		}
+	}()
+
+	select {
+	case res := <-result:
+		if conn, ok := res.(*grpc.ClientConn); ok {
+			return conn, nil
+		}
+		return nil, res.(error)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/crypto"
+)
+
+const scheduleLogSuffix = ".schedule.log"
+
+// DefaultSchedulerInterval is how often the scheduler checks for due payments
+const DefaultSchedulerInterval = 30 * time.Second
+
+// maxSchedulerBackoff is the longest the scheduler waits before running a failing schedule again
+const maxSchedulerBackoff = time.Hour
+
+// backoff delays the next attempt after a failure, doubling the delay after each failure
+type backoff struct {
+	failures int
+	next     time.Time
+}
+
+func (b *backoff) ready(now time.Time) bool {
+	return !now.Before(b.next)
+}
+
+func (b *backoff) failed(now time.Time, interval time.Duration) {
+	b.failures++
+	delay := interval
+	for i := 1; i < b.failures && delay < maxSchedulerBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxSchedulerBackoff {
+		delay = maxSchedulerBackoff
+	}
+	b.next = now.Add(delay)
+}
+
+// Schedules returns the scheduled payments stored in the wallet
+func (w *WalletBackend) Schedules() ([]common.ScheduledPayment, error) {
+	return w.wallet.GetSchedules()
+}
+
+// AddSchedule stores a new scheduled payment in the wallet. The schedule starts counting from the current layer and time.
+func (w *WalletBackend) AddSchedule(s common.ScheduledPayment) (*common.ScheduledPayment, error) {
+	info, err := w.GetMeshInfo()
+	if err != nil {
+		return nil, err
+	}
+	s.Id = crypto.UUIDString()[:8]
+	s.LastLayer = info.CurrentLayer
+	s.LastRun = time.Now()
+	if err := w.wallet.AddSchedule(s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RemoveSchedule deletes a scheduled payment from the wallet
+func (w *WalletBackend) RemoveSchedule(id string) error {
+	return w.wallet.RemoveSchedule(id)
+}
+
+// ScheduleLog returns all the recorded executions of scheduled payments
+func (w *WalletBackend) ScheduleLog() ([]common.ScheduleExecution, error) {
+	return common.ReadScheduleLog(w.wallet.WalletPath() + scheduleLogSuffix)
+}
+
+// RunDueSchedules submits the scheduled payments which are due and records their executions
+func (w *WalletBackend) RunDueSchedules() ([]common.ScheduleExecution, error) {
+	return w.runDueSchedules(w.foreground(), func(string) bool { return true })
+}
+
+// runDueSchedules runs the due scheduled payments for which ready returns true, with the calls and errors of c
+func (w *WalletBackend) runDueSchedules(c caller, ready func(id string) bool) ([]common.ScheduleExecution, error) {
+	if w.wallet == nil {
+		return nil, errors.New("no open wallet")
+	}
+	schedules, err := w.wallet.GetSchedules()
+	if err != nil || len(schedules) == 0 {
+		return nil, err
+	}
+
+	status, err := c.api.NodeStatus()
+	if err != nil {
+		return nil, err
+	}
+	if !status.IsSynced {
+		return nil, errors.New("node is not synced")
+	}
+	info, err := c.api.GetMeshInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	executions := make([]common.ScheduleExecution, 0)
+	now := time.Now()
+	for _, s := range schedules {
+		if !s.Due(info.CurrentLayer, info.LayerPerEpoch, now) || !ready(s.Id) {
+			continue
+		}
+		// the run is saved before the payment is sent, so that a payment is never sent twice
+		// because its run couldn't be saved
+		ran := s
+		ran.LastLayer = info.CurrentLayer
+		ran.LastRun = now
+		var e common.ScheduleExecution
+		if err := w.wallet.UpdateSchedule(ran); err != nil {
+			e = common.ScheduleExecution{ScheduleId: s.Id, Time: now, Layer: info.CurrentLayer, Recipient: s.Recipient, Amount: s.Amount,
+				Error: fmt.Sprintf("failed to save the run of the schedule: %v", err)}
+		} else if e = w.runSchedule(c, s, info.CurrentLayer); e.TxId == "" {
+			// nothing was sent: the schedule is due again
+			if err := w.wallet.UpdateSchedule(s); err != nil {
+				c.logError("failed to update schedule %s: %v", s.Id, err)
+			}
+		}
+		executions = append(executions, e)
+		if err := common.AppendScheduleLog(w.wallet.WalletPath()+scheduleLogSuffix, e); err != nil {
+			c.logError("failed to write schedule log: %v", err)
+		}
+	}
+	return executions, nil
+}
+
+func (w *WalletBackend) runSchedule(c caller, s common.ScheduledPayment, layer uint32) common.ScheduleExecution {
+	e := common.ScheduleExecution{ScheduleId: s.Id, Time: time.Now(), Layer: layer, Recipient: s.Recipient, Amount: s.Amount}
+	fail := func(err error) common.ScheduleExecution {
+		e.Error = err.Error()
+		return e
+	}
+
+	from, err := common.ParseAddress(s.Account)
+	if err != nil {
+		return fail(err)
+	}
+	to, err := common.ParseAddress(s.Recipient)
+	if err != nil {
+		return fail(err)
+	}
+	acc, err := w.AccountByAddress(from)
+	if err != nil {
+		return fail(err)
+	}
+	txState, err := w.transferNext(c, to, s.Amount, s.Fee, common.DefaultGasLimit, acc.PrivKey)
+	if err != nil {
+		return fail(err)
+	}
+	e.TxId = "0x" + hex.EncodeToString(txState.Id.Id)
+	return e
+}
+
+// StartScheduler runs the due scheduled payments every interval until the returned stop function is called.
+// Each execution is passed to report. stop returns once the scheduler is done, so that the wallet can be closed.
+// After a failure, the scheduler waits longer before trying again: twice the interval, then four times, up to an hour.
+// The scheduler runs in the background: Ctrl-C doesn't cancel its calls, and its errors don't fail the REPL commands.
+func (w *WalletBackend) StartScheduler(interval time.Duration, report func(common.ScheduleExecution)) (stop func()) {
+	c := w.background()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var run backoff
+		schedules := make(map[string]*backoff)
+		ready := func(id string) bool {
+			b, ok := schedules[id]
+			return !ok || b.ready(time.Now())
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if !run.ready(now) {
+					continue
+				}
+				executions, err := w.runDueSchedules(c, ready)
+				if err != nil {
+					run.failed(now, interval)
+					c.logError("scheduler: %v. Next attempt at %s", err, run.next.Format("15:04:05"))
+					continue
+				}
+				run = backoff{}
+				for _, e := range executions {
+					if e.Error == "" {
+						delete(schedules, e.ScheduleId)
+					} else {
+						if schedules[e.ScheduleId] == nil {
+							schedules[e.ScheduleId] = &backoff{}
+						}
+						schedules[e.ScheduleId].failed(now, interval)
+					}
+					report(e)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	now := time.Now()
+	var b backoff
+	assert.True(t, b.ready(now))
+
+	b.failed(now, time.Minute)
+	assert.False(t, b.ready(now.Add(59*time.Second)))
+	assert.True(t, b.ready(now.Add(time.Minute)))
+	b.failed(now, time.Minute)
+	b.failed(now, time.Minute)
+	assert.Equal(t, now.Add(4*time.Minute), b.next, "the delay doubles after each failure")
+
+	for i := 0; i < 20; i++ {
+		b.failed(now, time.Minute)
+	}
+	assert.Equal(t, now.Add(maxSchedulerBackoff), b.next)
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/log"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSOptions are the TLS settings of secure connections. The zero value verifies the server
+// certificate with the system CAs.
+type TLSOptions struct {
+	CACert     string `mapstructure:"ca"`          // PEM bundle of the CAs trusted instead of the system CAs
+	ClientCert string `mapstructure:"cert"`        // PEM client certificate, for mutual TLS
+	ClientKey  string `mapstructure:"key"`         // PEM key of the client certificate
+	ServerName string `mapstructure:"server_name"` // name checked against the server certificate and sent as SNI
+	MinVersion string `mapstructure:"min_version"` // minimum TLS version: 1.0, 1.1, 1.2 or 1.3
+}
+
+// IsSet returns true iff one of the options is set
+func (o TLSOptions) IsSet() bool {
+	return o != TLSOptions{}
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version such as 1.2
+func ParseTLSVersion(version string) (uint16, error) {
+	name := strings.TrimSpace(strings.TrimPrefix(version, "TLS"))
+	if !strings.Contains(name, ".") {
+		// 1.0 in a yaml file is read as 1
+		name += ".0"
+	}
+	v, ok := tlsVersions[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version %s. Use 1.0, 1.1, 1.2 or 1.3", version)
+	}
+	return v, nil
+}
+
+func tlsVersionName(version uint16) string {
+	for name, v := range tlsVersions {
+		if v == version {
+			return "TLS " + name
+		}
+	}
+	return fmt.Sprintf("TLS 0x%04x", version)
+}
+
+// Config returns the TLS config of the options, reading the certificate files
+func (o TLSOptions) Config() (*tls.Config, error) {
+	conf := &tls.Config{ServerName: o.ServerName}
+	if o.MinVersion != "" {
+		v, err := ParseTLSVersion(o.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		conf.MinVersion = v
+	}
+	if o.CACert != "" {
+		pem, err := ioutil.ReadFile(o.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in the CA bundle %s", o.CACert)
+		}
+		conf.RootCAs = pool
+	}
+	if o.ClientCert != "" || o.ClientKey != "" {
+		if o.ClientCert == "" || o.ClientKey == "" {
+			return nil, errors.New("the client certificate and its key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %v", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+// handshakeCredentials are transport credentials which report the state of each TLS handshake,
+// and its error when failed is set
+type handshakeCredentials struct {
+	credentials.TransportCredentials
+	handshake func(tls.ConnectionState)
+	failed    func(error)
+}
+
+func (c *handshakeCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn, info, err := c.TransportCredentials.ClientHandshake(ctx, authority, conn)
+	if err != nil {
+		if c.failed != nil {
+			c.failed(err)
+		}
+		return conn, info, err
+	}
+	if tlsInfo, ok := info.(credentials.TLSInfo); ok {
+		c.handshake(tlsInfo.State)
+	}
+	return conn, info, err
+}
+
+func (c *handshakeCredentials) Clone() credentials.TransportCredentials {
+	return &handshakeCredentials{c.TransportCredentials.Clone(), c.handshake, c.failed}
+}
+
+// describeTLS describes the negotiated TLS version and cipher suite and the certificate of the server
+func describeTLS(state *tls.ConnectionState) string {
+	s := fmt.Sprintf("%s, %s", tlsVersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if len(state.PeerCertificates) == 0 {
+		return s
+	}
+	cert := state.PeerCertificates[0]
+	return s + fmt.Sprintf(". Server certificate: %s, issued by %s, valid until %s, public key %s",
+		cert.Subject, cert.Issuer, cert.NotAfter.Format("2006-01-02"), common.KeyFingerprint(cert.RawSubjectPublicKeyInfo))
+}
+
+// checkPin checks the public key presented by a server against the known servers, pinning it if the server is new
+func checkPin(known *common.KnownServers, server string, state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%s presented no certificate", server)
+	}
+	cert := state.PeerCertificates[0]
+	fingerprint := common.KeyFingerprint(cert.RawSubjectPublicKeyInfo)
+	added, err := known.Check(server, fingerprint, cert.Subject.String())
+	if added {
+		log.Info("Pinned the public key of %s: %s", server, fingerprint)
+	}
+	return err
+}
+
+// ServerKey connects to a server with the TLS and proxy options of the client and returns the fingerprint
+// of its public key and the subject of its certificate, without checking the pinned keys
+func (c *gRPCClient) ServerKey(server string) (fingerprint, subject string, err error) {
+	return FetchServerKey(server, c.connectionOptions())
+}
+
+// FetchServerKey connects to a server with TLS, through the proxy of the options, and returns the fingerprint
+// of its public key and the subject of its certificate. The certificate is verified, but not checked against the pinned keys.
+func FetchServerKey(server string, options ConnectionOptions) (fingerprint, subject string, err error) {
+	conf, err := options.TLS.Config()
+	if err != nil {
+		return "", "", err
+	}
+	if conf.ServerName == "" {
+		if conf.ServerName, _, err = net.SplitHostPort(server); err != nil {
+			return "", "", err
+		}
+	}
+	proxyURL, err := proxyFor(options.Proxy, server)
+	if err != nil {
+		return "", "", err
+	}
+	dial, err := proxyDialer(proxyURL)
+	if err != nil {
+		return "", "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	rawConn, err := dial(ctx, server)
+	if err != nil {
+		return "", "", err
+	}
+	defer rawConn.Close()
+	deadline, _ := ctx.Deadline()
+	rawConn.SetDeadline(deadline)
+	conn := tls.Client(rawConn, conf)
+	if err := conn.Handshake(); err != nil {
+		return "", "", err
+	}
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return "", "", fmt.Errorf("%s presented no certificate", server)
+	}
+	cert := state.PeerCertificates[0]
+	return common.KeyFingerprint(cert.RawSubjectPublicKeyInfo), cert.Subject.String(), nil
+}
+
+func (c *gRPCClient) knownServers() (*common.KnownServers, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.known == nil {
+		return nil, errors.New("public key pinning is off. Use -pin or set pin in the profile")
+	}
+	return c.known, nil
+}
+
+// KnownServers returns the servers which have a pinned public key
+func (c *gRPCClient) KnownServers() ([]common.KnownServer, error) {
+	known, err := c.knownServers()
+	if err != nil {
+		return nil, err
+	}
+	return known.List(), nil
+}
+
+// TrustServer pins a public key for a server, replacing the pinned key. When the server is one of the
+// API servers, the client reconnects so that the new key is used.
+func (c *gRPCClient) TrustServer(server, fingerprint, subject string) error {
+	known, err := c.knownServers()
+	if err != nil {
+		return err
+	}
+	if err := known.Trust(server, fingerprint, subject); err != nil {
+		return err
+	}
+	for _, s := range c.connectionOptions().Servers {
+		if s == server {
+			return c.Connect()
+		}
+	}
+	return nil
+}
+
+// ForgetServer removes the pinned public key of a server. The next key it presents is pinned.
+func (c *gRPCClient) ForgetServer(server string) error {
+	known, err := c.knownServers()
+	if err != nil {
+		return err
+	}
+	return known.Forget(server)
+}
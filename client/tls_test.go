@@ -0,0 +1,202 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testCert is a certificate and its key, also saved as PEM files
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed CA certificate when parent is nil
+func newTestCert(t *testing.T, name string, parent *testCert, dnsNames ...string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	c := &testCert{cert: cert, key: key}
+	dir := t.TempDir()
+	c.certFile = filepath.Join(dir, name+".crt")
+	require.NoError(t, ioutil.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	c.keyFile = filepath.Join(dir, name+".key")
+	require.NoError(t, ioutil.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return c
+}
+
+// startTLSNode starts an API server which requires a client certificate issued by ca
+func startTLSNode(t *testing.T, ca, server *testCert) string {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate()},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(creds))
+	apitypes.RegisterNodeServiceServer(s, &testNode{synced: true})
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+func TestMutualTLS(t *testing.T) {
+	fastRetries(t)
+	ca := newTestCert(t, "test-ca", nil)
+	server := newTestCert(t, "api.internal", ca, "api.internal")
+	clientCert := newTestCert(t, "wallet", ca)
+	addr := startTLSNode(t, ca, server)
+
+	options := ConnectionOptions{
+		Servers: []string{addr},
+		Secure:  true,
+		TLS: TLSOptions{
+			CACert:     ca.certFile,
+			ClientCert: clientCert.certFile,
+			ClientKey:  clientCert.keyFile,
+			ServerName: "api.internal",
+			MinVersion: "1.2",
+		},
+	}
+	c := newGRPCClient(options)
+	require.NoError(t, c.Connect())
+	require.NoError(t, c.Echo())
+	info := c.ServerInfo()
+	assert.Contains(t, info, "TLS 1.3")
+	assert.Contains(t, info, "Server certificate: CN=api.internal, issued by CN=test-ca")
+	c.Close()
+
+	// the server certificate is not valid for the address of the server
+	noSNI := options
+	noSNI.TLS.ServerName = ""
+	assert.Error(t, newGRPCClient(noSNI).Connect())
+
+	// the server certificate is not issued by a trusted CA
+	otherCA := options
+	otherCA.TLS.CACert = newTestCert(t, "other-ca", nil).certFile
+	assert.Error(t, newGRPCClient(otherCA).Connect())
+}
+
+func TestTLSOptionsConfig(t *testing.T) {
+	conf, err := TLSOptions{}.Config()
+	require.NoError(t, err)
+	assert.Nil(t, conf.RootCAs, "the system CAs are used")
+
+	_, err = TLSOptions{ClientKey: "wallet.key"}.Config()
+	assert.Error(t, err)
+	_, err = TLSOptions{CACert: filepath.Join(t.TempDir(), "missing.pem")}.Config()
+	assert.Error(t, err)
+	_, err = TLSOptions{MinVersion: "1.4"}.Config()
+	assert.Error(t, err)
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	for version, expected := range map[string]uint16{"1.2": tls.VersionTLS12, "TLS1.3": tls.VersionTLS13, "1": tls.VersionTLS10} {
+		v, err := ParseTLSVersion(version)
+		require.NoError(t, err, version)
+		assert.Equal(t, expected, v, version)
+	}
+	_, err := ParseTLSVersion("ssl3")
+	assert.Error(t, err)
+}
+
+func TestPinning(t *testing.T) {
+	fastRetries(t)
+	ca := newTestCert(t, "test-ca", nil)
+	server := newTestCert(t, "api.internal", ca, "api.internal")
+	clientCert := newTestCert(t, "wallet", ca)
+	addr := startTLSNode(t, ca, server)
+	knownServers := filepath.Join(t.TempDir(), "known_servers.json")
+
+	options := ConnectionOptions{
+		Servers:      []string{addr},
+		Secure:       true,
+		TLS:          TLSOptions{CACert: ca.certFile, ClientCert: clientCert.certFile, ClientKey: clientCert.keyFile, ServerName: "api.internal"},
+		KnownServers: knownServers,
+	}
+	fingerprint := common.KeyFingerprint(server.cert.RawSubjectPublicKeyInfo)
+
+	// the key is pinned on first use
+	c := newGRPCClient(options)
+	require.NoError(t, c.Connect())
+	require.NoError(t, c.Echo())
+	assert.Contains(t, c.ServerInfo(), fingerprint)
+	known, err := c.KnownServers()
+	require.NoError(t, err)
+	require.Len(t, known, 1)
+	assert.Equal(t, fingerprint, known[0].Fingerprint)
+	c.Close()
+
+	// a changed key is refused
+	k, err := common.LoadKnownServers(knownServers)
+	require.NoError(t, err)
+	require.NoError(t, k.Trust(addr, "SHA256:other", ""))
+	c = newGRPCClient(options)
+	err = c.Connect()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "WARNING: the public key of "+addr+" changed")
+
+	// until it is trusted
+	presented, subject, err := c.ServerKey(addr)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, presented)
+	require.NoError(t, c.TrustServer(addr, presented, subject))
+	require.NoError(t, c.Echo())
+	c.Close()
+
+	// without pinning, the known servers are not used
+	options.KnownServers = ""
+	c = newGRPCClient(options)
+	require.NoError(t, c.Connect())
+	_, err = c.KnownServers()
+	assert.Error(t, err)
+	c.Close()
+}
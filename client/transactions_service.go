@@ -2,15 +2,22 @@ package client
 
 import (
 	"context"
+	"time"
 
 	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-// SubmitCoinTransaction submits a signed binary transaction to the node.
-func (c *gRPCClient) SubmitCoinTransaction(tx []byte) (*apitypes.TransactionState, error) {
+// SubmitTransaction submits a signed binary transaction to the node.
+func (c *gRPCClient) SubmitTransaction(tx []byte) (*apitypes.TransactionState, error) {
 
 	s := c.getTransactionServiceClient()
 	resp, err := s.SubmitTransaction(context.Background(), &apitypes.SubmitTransactionRequest{Transaction: tx})
+	if (isTransportError(err) && !notSent(err)) || status.Code(err) == codes.Canceled {
+		// the node may have received the transaction: sending it again could spend twice
+		return nil, status.Errorf(status.Code(err), "%s. The transaction may have reached the node: check its state before sending it again", status.Convert(err).Message())
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -33,5 +40,79 @@ func (c *gRPCClient) TransactionState(txId []byte, includeTx bool) (*apitypes.Tr
 		return nil, nil, err
 	}
 
-	return resp.TransactionsState[0], resp.Transactions[0], nil
+	var txState *apitypes.TransactionState
+	var tx *apitypes.Transaction
+	if len(resp.TransactionsState) > 0 {
+		txState = resp.TransactionsState[0]
+	}
+	if len(resp.Transactions) > 0 {
+		tx = resp.Transactions[0]
+	}
+	return txState, tx, nil
+}
+
+// interval between transaction state queries when the state stream is not available
+const txStatePollInterval = 5 * time.Second
+
+// WatchTransactionState calls update with the state of a transaction and again each time it changes,
+// until ctx is done or update returns false. State changes are streamed from the transaction service,
+// with polling as a fallback when the stream is not available.
+func (c *gRPCClient) WatchTransactionState(ctx context.Context, txId []byte, update func(*apitypes.TransactionState, *apitypes.Transaction) bool) error {
+	txState, tx, err := c.TransactionState(txId, true)
+	if err != nil {
+		return err
+	}
+	if !update(txState, tx) {
+		return nil
+	}
+	lastState := apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED
+	if txState != nil {
+		lastState = txState.State
+	}
+
+	s := c.getTransactionServiceClient()
+	stream, err := s.TransactionsStateStream(ctx, &apitypes.TransactionsStateStreamRequest{
+		TransactionId:       []*apitypes.TransactionId{{Id: txId}},
+		IncludeTransactions: true,
+	})
+	if err == nil {
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// the stream is not available, fall back to polling
+				break
+			}
+			if resp.TransactionState == nil || resp.TransactionState.State == lastState {
+				continue
+			}
+			lastState = resp.TransactionState.State
+			if !update(resp.TransactionState, resp.Transaction) {
+				return nil
+			}
+		}
+	}
+
+	ticker := time.NewTicker(txStatePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		txState, tx, err := c.TransactionState(txId, true)
+		if err != nil {
+			return err
+		}
+		if txState == nil || txState.State == lastState {
+			continue
+		}
+		lastState = txState.State
+		if !update(txState, tx) {
+			return nil
+		}
+	}
 }
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+const txJournalSuffix = ".txs"
+
+// txJournal returns the journal of submitted transactions kept next to the wallet file
+func (w *WalletBackend) txJournal() (*common.TxJournal, error) {
+	w.journalMu.Lock()
+	defer w.journalMu.Unlock()
+	if w.journal != nil {
+		return w.journal, nil
+	}
+	if w.wallet == nil || w.wallet.WalletPath() == "" {
+		return nil, errors.New("no wallet file to keep a transactions journal for")
+	}
+	journal, err := common.LoadTxJournal(w.wallet.WalletPath() + txJournalSuffix)
+	if err != nil {
+		return nil, err
+	}
+	w.journal = journal
+	return journal, nil
+}
+
+// recordTransaction adds a submitted transaction to the journal of the sending account
+func (w *WalletBackend) recordTransaction(sender gosmtypes.Address, txState *pb.TransactionState, tx *common.InnerSerializableSignedTransaction, replaces string) error {
+	return w.recordPending(sender, txState, common.PendingTransaction{
+		Recipient: tx.Recipient.String(),
+		Nonce:     tx.AccountNonce,
+		Amount:    tx.Amount,
+		Fee:       tx.Price,
+		GasLimit:  tx.GasLimit,
+		Replaces:  replaces,
+	})
+}
+
+// recordContractTransaction adds a submitted smart contract transaction to the journal of the sending account.
+// Its app or template is the recipient.
+func (w *WalletBackend) recordContractTransaction(sender gosmtypes.Address, txState *pb.TransactionState, tx *common.InnerSerializableContractTransaction) error {
+	return w.recordPending(sender, txState, common.PendingTransaction{
+		Recipient: tx.Account.String(),
+		Nonce:     tx.AccountNonce,
+		Amount:    tx.Amount,
+		Fee:       tx.Price,
+		GasLimit:  tx.GasLimit,
+	})
+}
+
+func (w *WalletBackend) recordPending(sender gosmtypes.Address, txState *pb.TransactionState, tx common.PendingTransaction) error {
+	if txState == nil || txState.Id == nil {
+		return nil
+	}
+	journal, err := w.txJournal()
+	if err != nil {
+		return fmt.Errorf("failed to open transactions journal: %v", err)
+	}
+	tx.Id = hex.EncodeToString(txState.Id.Id)
+	tx.Submitted = time.Now()
+	err = journal.Add(sender, tx)
+	if err != nil {
+		return fmt.Errorf("failed to record transaction in journal: %v", err)
+	}
+	return nil
+}
+
+// NextNonce returns the nonce to use for the next transaction sent from an account.
+// The projected nonce reported by the node is raised past any transaction pending in the journal.
+func (w *WalletBackend) NextNonce(address gosmtypes.Address) (uint64, error) {
+	return w.nextNonce(w.gRPCClient, address)
+}
+
+func (w *WalletBackend) nextNonce(api *gRPCClient, address gosmtypes.Address) (uint64, error) {
+	state, err := api.AccountState(address)
+	if err != nil {
+		return 0, err
+	}
+	journal, err := w.txJournal()
+	if err != nil {
+		return 0, err
+	}
+	return journal.NextNonce(address, state.StateProjected.Counter), nil
+}
+
+// checkNonceUnused fails if a pending transaction of the journal already uses nonce, such as when
+// another transfer was sent after the nonce was allocated
+func (w *WalletBackend) checkNonceUnused(address gosmtypes.Address, nonce uint64) error {
+	journal, err := w.txJournal()
+	if err != nil {
+		// transfers from wallets without a file have no journal
+		return nil
+	}
+	for _, tx := range journal.Pending(address) {
+		if !tx.Dropped && tx.Nonce == nonce {
+			return fmt.Errorf("nonce %d is already used by pending transaction 0x%s. Send the transaction again to use the next nonce", nonce, tx.Id)
+		}
+	}
+	return nil
+}
+
+// PendingTransactions returns the journal entries of an account
+func (w *WalletBackend) PendingTransactions(address gosmtypes.Address) ([]common.PendingTransaction, error) {
+	journal, err := w.txJournal()
+	if err != nil {
+		return nil, err
+	}
+	return journal.Pending(address), nil
+}
+
+// PendingTransaction returns the journal entry of a transaction sent from an account
+func (w *WalletBackend) PendingTransaction(address gosmtypes.Address, txId []byte) (*common.PendingTransaction, error) {
+	journal, err := w.txJournal()
+	if err != nil {
+		return nil, err
+	}
+	tx, ok := journal.Get(address, hex.EncodeToString(txId))
+	if !ok {
+		return nil, nil
+	}
+	return &tx, nil
+}
+
+// ReplaceTransaction re-signs a transaction which is still in the mempool with the same nonce and a higher fee.
+// The replacement repeats the original payment, or when cancel is set sends nothing to the sender itself.
+// The payment is taken from the journal. A transaction missing from the journal was not checked against
+// the spending policy, so its replacement is.
+func (w *WalletBackend) ReplaceTransaction(txId []byte, fee uint64, cancel bool) (*pb.TransactionState, error) {
+	txState, tx, err := w.TransactionState(txId, true)
+	if err != nil {
+		return nil, err
+	}
+	if txState == nil || tx == nil {
+		return nil, errors.New("unknown transaction")
+	}
+	if !bytes.Equal(txState.Id.GetId(), txId) || !bytes.Equal(tx.Id.GetId(), txId) {
+		return nil, errors.New("the node returned another transaction")
+	}
+	if txState.State != pb.TransactionState_TRANSACTION_STATE_MEMPOOL {
+		return nil, errors.New("only transactions waiting in the mempool can be replaced")
+	}
+	ct := tx.GetCoinTransfer()
+	if ct == nil {
+		return nil, errors.New("only coin transfers can be replaced")
+	}
+
+	sender := gosmtypes.BytesToAddress(tx.Sender.Address)
+	acc, err := w.AccountByAddress(sender)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient := gosmtypes.BytesToAddress(ct.Receiver.Address)
+	nonce, amount, originalFee, gasLimit := tx.Counter, tx.Amount.GetValue(), tx.GasOffered.GetGasPrice(), tx.GasOffered.GetGasProvided()
+	pending, err := w.PendingTransaction(sender, txId)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		if recipient, err = common.ParseAddress(pending.Recipient); err != nil {
+			return nil, fmt.Errorf("invalid recipient in the transactions journal: %v", err)
+		}
+		nonce, amount, originalFee, gasLimit = pending.Nonce, pending.Amount, pending.Fee, pending.GasLimit
+	}
+	if fee <= originalFee {
+		return nil, fmt.Errorf("the replacement fee must be higher than the original fee of %d", originalFee)
+	}
+
+	checkPolicy := pending == nil
+	originalCost := amount + originalFee
+	if cancel {
+		recipient = sender
+		amount = 0
+		checkPolicy = false
+	}
+	if gasLimit == 0 {
+		gasLimit = common.DefaultGasLimit
+	}
+
+	// the projected balance already pays for the original, the replacement only needs what it costs more
+	cost, carry := bits.Add64(amount, fee, 0)
+	if carry != 0 {
+		return nil, errors.New("the replacement fee is too high")
+	}
+	extra := uint64(0)
+	if cost > originalCost {
+		extra = cost - originalCost
+	}
+	preflight, err := w.PreflightFunds(sender, extra, 0, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if err := insufficientFunds(preflight); err != nil {
+		return nil, err
+	}
+	return w.transfer(recipient, nonce, amount, fee, gasLimit, acc.PrivKey, hex.EncodeToString(txId), checkPolicy)
+}
+
+// ReconcilePendingTransactions clears processed transactions from the journal of an account
+// and flags the ones which were dropped by the network
+func (w *WalletBackend) ReconcilePendingTransactions(address gosmtypes.Address) (confirmed, dropped []common.PendingTransaction, err error) {
+	journal, err := w.txJournal()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(journal.Pending(address)) == 0 {
+		return nil, nil, nil
+	}
+	state, err := w.AccountState(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	return journal.Reconcile(address, state.StateCurrent.Counter, func(txId []byte) (pb.TransactionState_TransactionState, error) {
+		txState, _, err := w.TransactionState(txId, false)
+		if err != nil {
+			return pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED, err
+		}
+		if txState == nil {
+			return pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED, nil
+		}
+		return txState.State, nil
+	})
+}
+
+// ClearDroppedTransactions removes the dropped transactions from the journal of an account
+func (w *WalletBackend) ClearDroppedTransactions(address gosmtypes.Address) error {
+	journal, err := w.txJournal()
+	if err != nil {
+		return err
+	}
+	return journal.ClearDropped(address)
+}
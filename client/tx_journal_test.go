@@ -0,0 +1,25 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNonceUnused(t *testing.T) {
+	journal, err := common.LoadTxJournal(filepath.Join(t.TempDir(), "wallet.json.txs"))
+	require.NoError(t, err)
+	sender := gosmtypes.BytesToAddress([]byte{1})
+	require.NoError(t, journal.Add(sender, common.PendingTransaction{Id: "aa", Nonce: 4}))
+	require.NoError(t, journal.Add(sender, common.PendingTransaction{Id: "bb", Nonce: 5, Dropped: true}))
+	w := &WalletBackend{journal: journal}
+
+	// a scheduled payment took the nonce the REPL allocated
+	assert.Error(t, w.checkNonceUnused(sender, 4))
+	assert.NoError(t, w.checkNonceUnused(sender, 5), "dropped transactions don't hold their nonce")
+	assert.NoError(t, w.checkNonceUnused(gosmtypes.BytesToAddress([]byte{2}), 4))
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+var (
+	// ErrAddressLength is returned when an address does not hold exactly 20 bytes
+	ErrAddressLength = fmt.Errorf("address must be %d hex characters long", gosmtypes.AddressLength*2)
+	// ErrAddressNotHex is returned when an address contains non hex characters
+	ErrAddressNotHex = errors.New("address contains non hex characters")
+	// ErrAddressChecksum is returned when a mixed case address does not match its checksum
+	ErrAddressChecksum = errors.New("address checksum mismatch. Please check the address for typos")
+)
+
+// ParseAddress parses a hex account address, with or without the 0x prefix.
+// Unlike gosmtypes.HexToAddress it rejects input of the wrong length or with non hex characters.
+// An address in mixed case must match the checksummed form returned by Address.Hex(),
+// an all lower or all upper case address is accepted as is.
+func ParseAddress(s string) (gosmtypes.Address, error) {
+	s = trimHexPrefix(s)
+	if len(s) != gosmtypes.AddressLength*2 {
+		return gosmtypes.Address{}, ErrAddressLength
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return gosmtypes.Address{}, ErrAddressNotHex
+	}
+	addr := gosmtypes.BytesToAddress(b)
+	if s != strings.ToLower(s) && s != strings.ToUpper(s) && addr.Hex()[2:] != s {
+		return gosmtypes.Address{}, ErrAddressChecksum
+	}
+	return addr, nil
+}
+
+// IsChecksummed returns true iff s is an address in its checksummed display form
+func IsChecksummed(s string) bool {
+	addr, err := ParseAddress(s)
+	if err != nil {
+		return false
+	}
+	return trimHexPrefix(s) == addr.Hex()[2:]
+}
+
+// trimHexPrefix trims the spaces around s and its 0x or 0X prefix
+func trimHexPrefix(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	return s
+}
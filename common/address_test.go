@@ -0,0 +1,45 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const checksummedAddress = "0x92A1836674caD602f1931f071938F40CEf2e9c0F"
+
+func TestParseAddress(t *testing.T) {
+	valid := []string{
+		checksummedAddress,
+		checksummedAddress[2:],
+		strings.ToLower(checksummedAddress),
+		"0x" + strings.ToUpper(checksummedAddress[2:]),
+		"  " + checksummedAddress + " ",
+	}
+	for _, s := range valid {
+		addr, err := ParseAddress(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, checksummedAddress, addr.String(), s)
+	}
+
+	invalid := map[string]error{
+		"":                            ErrAddressLength,
+		"0x":                          ErrAddressLength,
+		checksummedAddress[:40]:       ErrAddressLength,
+		checksummedAddress + "00":     ErrAddressLength,
+		checksummedAddress[:41] + "g": ErrAddressNotHex,
+		"0x92a1836674caD602f1931f071938F40CEf2e9c0F": ErrAddressChecksum,
+	}
+	for s, expected := range invalid {
+		_, err := ParseAddress(s)
+		assert.Equal(t, expected, err, s)
+	}
+}
+
+func TestIsChecksummed(t *testing.T) {
+	assert.True(t, IsChecksummed(checksummedAddress))
+	assert.True(t, IsChecksummed("0X"+checksummedAddress[2:]))
+	assert.False(t, IsChecksummed(strings.ToLower(checksummedAddress)))
+	assert.False(t, IsChecksummed("0x1234"))
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// OneSmesh is the number of Smidge in one SMH
+const OneSmesh = 1000000000000
+
+const smidgeDecimals = 12
+
+// ErrInvalidAmount is returned when an amount can't be parsed
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// ParseAmount parses an amount of coins into Smidge.
+// A bare number or a number followed by "Smidge" is an amount in Smidge, e.g. "1000" or "1000Smidge".
+// A number followed by "SMH" is an amount in SMH and may have up to 12 decimals, e.g. "2.5SMH" or "2.5 SMH".
+func ParseAmount(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasSuffix(lower, "smh"):
+		return parseSmesh(strings.TrimSpace(s[:len(s)-3]))
+	case strings.HasSuffix(lower, "smidge"):
+		s = strings.TrimSpace(s[:len(s)-6])
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %s", ErrInvalidAmount, s)
+	}
+	return v, nil
+}
+
+func parseSmesh(s string) (uint64, error) {
+	parts := strings.SplitN(s, ".", 2)
+	whole, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || whole > math.MaxUint64/OneSmesh {
+		return 0, fmt.Errorf("%v: %s SMH", ErrInvalidAmount, s)
+	}
+	frac := uint64(0)
+	if len(parts) == 2 {
+		digits := parts[1]
+		if len(digits) == 0 || len(digits) > smidgeDecimals {
+			return 0, fmt.Errorf("%v: %s SMH", ErrInvalidAmount, s)
+		}
+		digits += strings.Repeat("0", smidgeDecimals-len(digits))
+		if frac, err = strconv.ParseUint(digits, 10, 64); err != nil {
+			return 0, fmt.Errorf("%v: %s SMH", ErrInvalidAmount, s)
+		}
+	}
+	total := whole * OneSmesh
+	if total+frac < total {
+		return 0, fmt.Errorf("%v: %s SMH", ErrInvalidAmount, s)
+	}
+	return total + frac, nil
+}
+
+// Display units of amounts
+const (
+	UnitsAuto   = "auto"   // SMH from 0.01 SMH up, Smidge below
+	UnitsSMH    = "smh"    // always SMH
+	UnitsSmidge = "smidge" // always Smidge
+)
+
+// amountUnits are the units of FormatAmount
+var amountUnits = UnitsAuto
+
+// CheckAmountUnits returns an error if units are not display units
+func CheckAmountUnits(units string) error {
+	switch units {
+	case UnitsAuto, UnitsSMH, UnitsSmidge:
+		return nil
+	}
+	return fmt.Errorf("invalid units %s. Use %s, %s or %s", units, UnitsAuto, UnitsSMH, UnitsSmidge)
+}
+
+// SetAmountUnits sets the units amounts are displayed in
+func SetAmountUnits(units string) error {
+	if err := CheckAmountUnits(units); err != nil {
+		return err
+	}
+	amountUnits = units
+	return nil
+}
+
+// FormatAmount formats an amount of Smidge for display in the display units
+func FormatAmount(val uint64) string {
+	switch amountUnits {
+	case UnitsSMH:
+		return fmt.Sprintf("%d.%012d SMH", val/OneSmesh, val%OneSmesh)
+	case UnitsSmidge:
+		return fmt.Sprint(val, " Smidge")
+	}
+	if val >= OneSmesh {
+		return fmt.Sprintf("%d.%012d SMH", val/OneSmesh, val%OneSmesh)
+	} else if val >= OneSmesh/100 {
+		return fmt.Sprintf("0.%012d SMH", val%OneSmesh)
+	}
+	return fmt.Sprint(val, " Smidge")
+}
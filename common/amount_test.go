@@ -0,0 +1,46 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmount(t *testing.T) {
+	valid := map[string]uint64{
+		"0":                        0,
+		"1000":                     1000,
+		"1000Smidge":               1000,
+		"1000 smidge":              1000,
+		"2SMH":                     2 * OneSmesh,
+		"2.5SMH":                   2500000000000,
+		"2.5 smh":                  2500000000000,
+		"0.000000000001SMH":        1,
+		"18446744.073709551615SMH": 18446744073709551615,
+	}
+	for s, expected := range valid {
+		v, err := ParseAmount(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, v, s)
+	}
+
+	for _, s := range []string{"", "-1", "1.5", "abc", "1.SMH", "0.0000000000001SMH", "18446745SMH", "18446744.073709551616SMH", "1e3"} {
+		_, err := ParseAmount(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "999 Smidge", FormatAmount(999))
+	assert.Equal(t, "0.010000000000 SMH", FormatAmount(OneSmesh/100))
+	assert.Equal(t, "2.500000000000 SMH", FormatAmount(2500000000000))
+}
+
+func TestAmountUnits(t *testing.T) {
+	defer SetAmountUnits(UnitsAuto)
+	assert.NoError(t, SetAmountUnits(UnitsSMH))
+	assert.Equal(t, "0.000000000999 SMH", FormatAmount(999))
+	assert.NoError(t, SetAmountUnits(UnitsSmidge))
+	assert.Equal(t, "2500000000000 Smidge", FormatAmount(2500000000000))
+	assert.Error(t, SetAmountUnits("btc"))
+}
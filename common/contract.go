@@ -0,0 +1,137 @@
+package common
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// SelectorSize is the size of the function selector which starts the data of an app call
+const SelectorSize = 4
+
+// ContractCall is the decoded payload of a smart contract transaction
+type ContractCall struct {
+	Kind        string            // readable transaction type
+	AccountRole string            // what the account of the transaction is: an app or a template
+	Account     gosmtypes.Address // address of the app or template
+	Data        []byte            // packed binary arguments, including the function selector of an app call
+	Selector    []byte            // function selector of an app call, nil for other transactions
+	Args        []byte            // packed binary arguments which follow the selector of an app call
+}
+
+var contractKinds = map[apitypes.SmartContractTransaction_TransactionType]struct{ kind, role string }{
+	apitypes.SmartContractTransaction_TRANSACTION_TYPE_UNSPECIFIED:     {"Unspecified", "Account"},
+	apitypes.SmartContractTransaction_TRANSACTION_TYPE_APP:             {"App call", "App"},
+	apitypes.SmartContractTransaction_TRANSACTION_TYPE_APP_SPAWN_APP:   {"Spawn app", "Template"},
+	apitypes.SmartContractTransaction_TRANSACTION_TYPE_DEPLOY_TEMPLATE: {"Deploy template", "Template"},
+}
+
+// DecodeContractCall decodes the payload of a smart contract transaction
+func DecodeContractCall(sct *apitypes.SmartContractTransaction) ContractCall {
+	return newContractCall(sct.Type, gosmtypes.BytesToAddress(sct.AccountId.GetAddress()), sct.Data)
+}
+
+// Call decodes the payload of the transaction
+func (tx *InnerSerializableContractTransaction) Call() ContractCall {
+	return newContractCall(apitypes.SmartContractTransaction_TransactionType(tx.Type), tx.Account, tx.Data)
+}
+
+func newContractCall(t apitypes.SmartContractTransaction_TransactionType, account gosmtypes.Address, data []byte) ContractCall {
+	c := ContractCall{
+		Kind:        t.String(),
+		AccountRole: "Account",
+		Account:     account,
+		Data:        data,
+	}
+	if k, ok := contractKinds[t]; ok {
+		c.Kind, c.AccountRole = k.kind, k.role
+	}
+	if t == apitypes.SmartContractTransaction_TRANSACTION_TYPE_APP && len(data) >= SelectorSize {
+		c.Selector, c.Args = data[:SelectorSize], data[SelectorSize:]
+	}
+	return c
+}
+
+// Function returns the name of the function an app call calls, or its selector in hex when known doesn't name it.
+// It returns an empty string for transactions which don't call a function.
+func (c ContractCall) Function(known ContractFunctions) string {
+	if c.Selector == nil {
+		return ""
+	}
+	if name, ok := known[hex.EncodeToString(c.Selector)]; ok {
+		return name
+	}
+	return "0x" + hex.EncodeToString(c.Selector)
+}
+
+// FunctionSelector returns the selector of the app function with the given name:
+// the first bytes of the sha256 hash of the name
+func FunctionSelector(name string) []byte {
+	h := sha256.Sum256([]byte(name))
+	return h[:SelectorSize]
+}
+
+// ContractFunctions names app functions by their selector in hex, so that app calls show the function they call
+type ContractFunctions map[string]string
+
+// Add adds the functions with the given names
+func (f ContractFunctions) Add(names ...string) {
+	for _, name := range names {
+		f[hex.EncodeToString(FunctionSelector(name))] = name
+	}
+}
+
+// ReadContractFunctions reads function names, one per line. Blank lines and lines starting with # are skipped.
+func ReadContractFunctions(r io.Reader) (ContractFunctions, error) {
+	f := make(ContractFunctions)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		f.Add(name)
+	}
+	return f, scanner.Err()
+}
+
+// EncodeAppCall packs the data of an app call from the function, given by name or as a 0x prefixed selector,
+// and its packed binary arguments
+func EncodeAppCall(function string, args []byte) ([]byte, error) {
+	if function == "" {
+		return nil, errors.New("missing function")
+	}
+	selector := FunctionSelector(function)
+	if strings.HasPrefix(function, "0x") {
+		var err error
+		if selector, err = ParseHex(function); err != nil || len(selector) != SelectorSize {
+			return nil, fmt.Errorf("invalid function selector %s. Use %d bytes of hex", function, SelectorSize)
+		}
+	}
+	return append(append(make([]byte, 0, len(selector)+len(args)), selector...), args...), nil
+}
+
+// ParseHex decodes hex data, with or without a 0x prefix
+func ParseHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// HexLines splits data into lines of hex with width bytes each
+func HexLines(data []byte, width int) []string {
+	lines := make([]string, 0, (len(data)+width-1)/width)
+	for start := 0; start < len(data); start += width {
+		end := start + width
+		if end > len(data) {
+			end = len(data)
+		}
+		lines = append(lines, hex.EncodeToString(data[start:end]))
+	}
+	return lines
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeContractCall(t *testing.T) {
+	addr, _ := ParseAddress(checksummedAddress)
+	c := DecodeContractCall(&apitypes.SmartContractTransaction{
+		Type:      apitypes.SmartContractTransaction_TRANSACTION_TYPE_APP_SPAWN_APP,
+		AccountId: &apitypes.AccountId{Address: addr.Bytes()},
+		Data:      []byte{1, 2, 3},
+	})
+	assert.Equal(t, "Spawn app", c.Kind)
+	assert.Equal(t, "Template", c.AccountRole)
+	assert.Equal(t, addr, c.Account)
+	assert.Equal(t, []byte{1, 2, 3}, c.Data)
+	assert.Nil(t, c.Selector)
+	assert.Empty(t, c.Function(nil))
+
+	c = DecodeContractCall(&apitypes.SmartContractTransaction{Type: 9})
+	assert.Equal(t, "9", c.Kind)
+	assert.Equal(t, "Account", c.AccountRole)
+}
+
+func TestHexLines(t *testing.T) {
+	assert.Empty(t, HexLines(nil, 4))
+	assert.Equal(t, []string{"01020304", "05"}, HexLines([]byte{1, 2, 3, 4, 5}, 4))
+}
+
+func TestAppCallFunction(t *testing.T) {
+	data, err := EncodeAppCall("transfer", []byte{7, 8})
+	assert.NoError(t, err)
+	assert.Equal(t, append(FunctionSelector("transfer"), 7, 8), data)
+
+	tx := InnerSerializableContractTransaction{Type: uint32(apitypes.SmartContractTransaction_TRANSACTION_TYPE_APP), Data: data}
+	c := tx.Call()
+	assert.Equal(t, "App call", c.Kind)
+	assert.Equal(t, FunctionSelector("transfer"), c.Selector)
+	assert.Equal(t, []byte{7, 8}, c.Args)
+
+	known, err := ReadContractFunctions(strings.NewReader("# token\n\nmint\ntransfer\n"))
+	assert.NoError(t, err)
+	assert.Len(t, known, 2)
+	assert.Equal(t, "transfer", c.Function(known))
+	assert.Equal(t, "0x"+hex.EncodeToString(FunctionSelector("transfer")), c.Function(nil))
+
+	data, err = EncodeAppCall("0x01020304", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4}, data)
+	_, err = EncodeAppCall("0x0102", nil)
+	assert.Error(t, err)
+	_, err = EncodeAppCall("", nil)
+	assert.Error(t, err)
+}
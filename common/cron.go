@@ -0,0 +1,111 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSpec is a parsed standard 5 field cron expression: minute hour day-of-month month day-of-week
+type CronSpec struct {
+	minute, hour, dom, month, dow uint64 // bit sets of the allowed values
+	domAny, dowAny                bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// ParseCron parses a cron expression such as "0 12 * * 1-5" or "*/15 * * * *".
+// Each field accepts *, single values, ranges, comma separated lists and /step suffixes.
+func ParseCron(expr string) (*CronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have 5 fields, got %d", len(fields))
+	}
+	sets := make([]uint64, len(fields))
+	for i, f := range fields {
+		set, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron field %q: %v", f, err)
+		}
+		sets[i] = set
+	}
+	return &CronSpec{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	set := uint64(0)
+	for _, part := range strings.Split(field, ",") {
+		step, stepped := 1, false
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step")
+			}
+			part, stepped = part[:i], true
+		}
+		lo, hi := bounds.min, bounds.max
+		if part != "*" {
+			var err error
+			values := strings.SplitN(part, "-", 2)
+			if lo, err = strconv.Atoi(values[0]); err != nil {
+				return 0, fmt.Errorf("invalid value")
+			}
+			hi = lo
+			if len(values) == 2 {
+				if hi, err = strconv.Atoi(values[1]); err != nil {
+					return 0, fmt.Errorf("invalid range")
+				}
+			} else if stepped {
+				// as in standard cron, a/n means a-max/n
+				hi = bounds.max
+			}
+		}
+		if lo < bounds.min || hi > bounds.max || lo > hi {
+			return 0, fmt.Errorf("value out of range %d-%d", bounds.min, bounds.max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Matches returns true iff the cron expression fires at the minute of t
+func (c *CronSpec) Matches(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 || c.hour&(1<<uint(t.Hour())) == 0 || c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	// as in standard cron, when both days are restricted either one may match
+	if !c.domAny && !c.dowAny {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Next returns the first time after t at which the cron expression fires, or the zero time
+// if it doesn't fire in the following 4 years
+func (c *CronSpec) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(4, 0, 0)
+	for ; t.Before(end); t = t.Add(time.Minute) {
+		if c.Matches(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expr := range []string{"* * * * *", "*/15 0-6 1,15 * 1-5", "0 12 * 1-12/3 0", "59 23 31 12 6"} {
+		_, err := ParseCron(expr)
+		assert.NoError(t, err, expr)
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 7", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	spec, err := ParseCron("30 9 * * 1-5")
+	require.NoError(t, err)
+	monday := time.Date(2021, 1, 25, 9, 30, 0, 0, time.UTC)
+	assert.True(t, spec.Matches(monday))
+	assert.False(t, spec.Matches(monday.Add(time.Minute)))
+	assert.False(t, spec.Matches(monday.AddDate(0, 0, 5))) // saturday
+
+	// either day field matches when both are restricted
+	spec, err = ParseCron("0 0 1 * 0")
+	require.NoError(t, err)
+	assert.True(t, spec.Matches(time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)))  // 1st, a monday
+	assert.True(t, spec.Matches(time.Date(2021, 2, 7, 0, 0, 0, 0, time.UTC)))  // sunday
+	assert.False(t, spec.Matches(time.Date(2021, 2, 8, 0, 0, 0, 0, time.UTC))) // monday
+}
+
+func TestCronNext(t *testing.T) {
+	spec, err := ParseCron("*/15 * * * *")
+	require.NoError(t, err)
+	from := time.Date(2021, 1, 25, 9, 31, 20, 0, time.UTC)
+	assert.Equal(t, time.Date(2021, 1, 25, 9, 45, 0, 0, time.UTC), spec.Next(from))
+	assert.Equal(t, time.Date(2021, 1, 25, 10, 0, 0, 0, time.UTC), spec.Next(spec.Next(from)))
+}
+
+func TestCronStepFromValue(t *testing.T) {
+	spec, err := ParseCron("5/20 * * * *")
+	require.NoError(t, err)
+	from := time.Date(2021, 1, 25, 9, 6, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2021, 1, 25, 9, 25, 0, 0, time.UTC), spec.Next(from))
+	assert.Equal(t, time.Date(2021, 1, 25, 9, 45, 0, 0, time.UTC), spec.Next(spec.Next(from)))
+	assert.Equal(t, time.Date(2021, 1, 25, 10, 5, 0, 0, time.UTC), spec.Next(spec.Next(spec.Next(from))))
+}
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export file formats
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// TxRecord is a mesh transaction of a wallet account as written by an export
+type TxRecord struct {
+	Account           string    `json:"account"`
+	AccountName       string    `json:"accountName"`
+	Layer             uint32    `json:"layer"`
+	Time              time.Time `json:"time"`
+	Id                string    `json:"id"`
+	Direction         string    `json:"direction"`
+	Counterparty      string    `json:"counterparty"`
+	CounterpartyLabel string    `json:"counterpartyLabel"`
+	Amount            uint64    `json:"amount"`
+	Fee               uint64    `json:"fee"`
+	Result            string    `json:"result"`
+}
+
+// RewardRecord is a reward paid to a wallet account as written by an export
+type RewardRecord struct {
+	Account     string    `json:"account"`
+	AccountName string    `json:"accountName"`
+	Layer       uint32    `json:"layer"`
+	Time        time.Time `json:"time"`
+	Total       uint64    `json:"total"`
+	LayerReward uint64    `json:"layerReward"`
+	Fees        uint64    `json:"fees"`
+}
+
+// LayerTime returns the start time of a layer
+func LayerTime(genesisTime uint64, layerDuration uint64, layer uint32) time.Time {
+	return time.Unix(int64(genesisTime+uint64(layer)*layerDuration), 0).UTC()
+}
+
+// LayerAt returns the layer in progress at time t. Times before genesis are in layer 0.
+func LayerAt(genesisTime uint64, layerDuration uint64, t time.Time) uint32 {
+	if layerDuration == 0 || t.Unix() < int64(genesisTime) {
+		return 0
+	}
+	return uint32((uint64(t.Unix()) - genesisTime) / layerDuration)
+}
+
+// ParseLayerBound parses the bound of a layer range given as a layer number, a date (2006-01-02)
+// or an RFC3339 time. A date used as the end of a range includes the whole day.
+func ParseLayerBound(s string, end bool, genesisTime uint64, layerDuration uint64) (uint32, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return uint32(n), nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		if end {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return LayerAt(genesisTime, layerDuration, t), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return LayerAt(genesisTime, layerDuration, t), nil
+	}
+	return 0, fmt.Errorf("invalid layer or date %s. Use a layer number, YYYY-MM-DD or an RFC3339 time", s)
+}
+
+// ExportFormat returns the export format to use for fileName. An empty format is taken from the file extension.
+func ExportFormat(fileName string, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	}
+	switch format {
+	case ExportCSV, ExportJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown export format %s. Use csv or json", format)
+}
+
+// WriteTxRecords writes transaction records in format
+func WriteTxRecords(w io.Writer, format string, records []TxRecord) error {
+	if format == ExportJSON {
+		return writeJSONRecords(w, records)
+	}
+	rows := [][]string{{"account", "account_name", "layer", "time", "id", "direction", "counterparty", "counterparty_label", "amount", "fee", "result"}}
+	for _, r := range records {
+		rows = append(rows, []string{r.Account, r.AccountName, strconv.FormatUint(uint64(r.Layer), 10), r.Time.Format(time.RFC3339),
+			r.Id, r.Direction, r.Counterparty, r.CounterpartyLabel, strconv.FormatUint(r.Amount, 10), strconv.FormatUint(r.Fee, 10), r.Result})
+	}
+	return csv.NewWriter(w).WriteAll(rows)
+}
+
+// WriteRewardRecords writes reward records in format
+func WriteRewardRecords(w io.Writer, format string, records []RewardRecord) error {
+	if format == ExportJSON {
+		return writeJSONRecords(w, records)
+	}
+	rows := [][]string{{"account", "account_name", "layer", "time", "total", "layer_reward", "fees"}}
+	for _, r := range records {
+		rows = append(rows, []string{r.Account, r.AccountName, strconv.FormatUint(uint64(r.Layer), 10), r.Time.Format(time.RFC3339),
+			strconv.FormatUint(r.Total, 10), strconv.FormatUint(r.LayerReward, 10), strconv.FormatUint(r.Fees, 10)})
+	}
+	return csv.NewWriter(w).WriteAll(rows)
+}
+
+func writeJSONRecords(w io.Writer, records interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
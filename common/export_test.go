@@ -0,0 +1,72 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayerTime(t *testing.T) {
+	genesis := uint64(time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC).Unix())
+	assert.Equal(t, time.Date(2020, 11, 1, 0, 5, 0, 0, time.UTC), LayerTime(genesis, 30, 10))
+	assert.Equal(t, uint32(10), LayerAt(genesis, 30, LayerTime(genesis, 30, 10).Add(29*time.Second)))
+	assert.Equal(t, uint32(0), LayerAt(genesis, 30, time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)))
+
+	l, err := ParseLayerBound("42", false, genesis, 30)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(42), l)
+	l, err = ParseLayerBound("2020-11-02", false, genesis, 30)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2880), l)
+	l, err = ParseLayerBound("2020-11-02", true, genesis, 30)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(5759), l)
+	l, err = ParseLayerBound("2020-11-01T01:00:00Z", false, genesis, 30)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(120), l)
+	_, err = ParseLayerBound("yesterday", false, genesis, 30)
+	assert.Error(t, err)
+}
+
+func TestExportFormat(t *testing.T) {
+	f, err := ExportFormat("txs.CSV", "")
+	require.NoError(t, err)
+	assert.Equal(t, ExportCSV, f)
+	f, err = ExportFormat("txs.out", ExportJSON)
+	require.NoError(t, err)
+	assert.Equal(t, ExportJSON, f)
+	_, err = ExportFormat("txs.xml", "")
+	assert.Error(t, err)
+}
+
+func TestWriteTxRecords(t *testing.T) {
+	records := []TxRecord{{
+		Account: checksummedAddress, AccountName: "main", Layer: 12, Time: time.Unix(1604188800, 0).UTC(),
+		Id: "abcd", Direction: TxOut, Counterparty: checksummedAddress, CounterpartyLabel: "alice, bob", Amount: 100, Fee: 1,
+	}}
+
+	var b bytes.Buffer
+	require.NoError(t, WriteTxRecords(&b, ExportCSV, records))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "account,account_name,layer,time,id,direction,counterparty,counterparty_label,amount,fee,result", lines[0])
+	assert.Equal(t, checksummedAddress+",main,12,2020-11-01T00:00:00Z,abcd,out,"+checksummedAddress+",\"alice, bob\",100,1,", lines[1])
+
+	b.Reset()
+	require.NoError(t, WriteTxRecords(&b, ExportJSON, records))
+	var decoded []TxRecord
+	require.NoError(t, json.Unmarshal(b.Bytes(), &decoded))
+	assert.Equal(t, records, decoded)
+}
+
+func TestWriteRewardRecords(t *testing.T) {
+	records := []RewardRecord{{Account: checksummedAddress, Layer: 5, Time: time.Unix(1604188800, 0).UTC(), Total: 110, LayerReward: 100, Fees: 10}}
+	var b bytes.Buffer
+	require.NoError(t, WriteRewardRecords(&b, ExportCSV, records))
+	assert.Equal(t, "account,account_name,layer,time,total,layer_reward,fees\n"+checksummedAddress+",,5,2020-11-01T00:00:00Z,110,100,10\n", b.String())
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"errors"
+	"sort"
+)
+
+// DefaultFee is the transaction fee used when there is nothing to base an estimate on
+const DefaultFee = 1
+
+// DefaultGasLimit is the gas limit of a coin transfer
+const DefaultGasLimit = 100
+
+// Fee presets
+const (
+	FeeLow    = "low"
+	FeeNormal = "normal"
+	FeeFast   = "fast"
+)
+
+// FeePresets lists the fee presets from cheapest to fastest
+var FeePresets = []string{FeeLow, FeeNormal, FeeFast}
+
+// FeeEstimate holds the suggested fees for each preset
+type FeeEstimate struct {
+	Low     uint64
+	Normal  uint64
+	Fast    uint64
+	Samples int // number of recent transactions the estimate is based on
+}
+
+// EstimateFees suggests fees based on the fees of recent transactions.
+// Low is the 25th percentile, normal the median and fast the 90th percentile of the samples.
+// Zero fees are ignored and every preset is at least DefaultFee.
+func EstimateFees(fees []uint64) FeeEstimate {
+	samples := make([]uint64, 0, len(fees))
+	for _, f := range fees {
+		if f > 0 {
+			samples = append(samples, f)
+		}
+	}
+	if len(samples) == 0 {
+		return FeeEstimate{Low: DefaultFee, Normal: DefaultFee, Fast: DefaultFee}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	percentile := func(p int) uint64 {
+		v := samples[(len(samples)-1)*p/100]
+		if v < DefaultFee {
+			return DefaultFee
+		}
+		return v
+	}
+	return FeeEstimate{
+		Low:     percentile(25),
+		Normal:  percentile(50),
+		Fast:    percentile(90),
+		Samples: len(samples),
+	}
+}
+
+// Preset returns the fee of a named preset, falling back to the normal fee
+func (e FeeEstimate) Preset(name string) uint64 {
+	switch name {
+	case FeeLow:
+		return e.Low
+	case FeeFast:
+		return e.Fast
+	}
+	return e.Normal
+}
+
+// ErrNothingToSweep is returned when a balance doesn't cover the fee of a sweep
+var ErrNothingToSweep = errors.New("balance is too low to cover the transaction fee")
+
+// SweepAmount returns the largest amount which can be sent from a balance with the given fee.
+// The node only applies a transaction when the balance exceeds amount plus fee, so 1 Smidge is left behind.
+func SweepAmount(balance, fee uint64) (uint64, error) {
+	if balance <= fee+1 {
+		return 0, ErrNothingToSweep
+	}
+	return balance - fee - 1, nil
+}
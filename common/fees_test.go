@@ -0,0 +1,36 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateFees(t *testing.T) {
+	e := EstimateFees(nil)
+	assert.Equal(t, FeeEstimate{Low: DefaultFee, Normal: DefaultFee, Fast: DefaultFee}, e)
+
+	e = EstimateFees([]uint64{0, 0})
+	assert.Equal(t, 0, e.Samples)
+
+	e = EstimateFees([]uint64{10, 1, 9, 2, 8, 3, 7, 4, 6, 5, 0})
+	assert.Equal(t, 10, e.Samples)
+	assert.Equal(t, uint64(3), e.Low)
+	assert.Equal(t, uint64(5), e.Normal)
+	assert.Equal(t, uint64(9), e.Fast)
+
+	assert.Equal(t, e.Low, e.Preset(FeeLow))
+	assert.Equal(t, e.Fast, e.Preset(FeeFast))
+	assert.Equal(t, e.Normal, e.Preset("unknown"))
+}
+
+func TestSweepAmount(t *testing.T) {
+	amount, err := SweepAmount(100, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(94), amount)
+
+	_, err = SweepAmount(6, 5)
+	assert.Equal(t, ErrNothingToSweep, err)
+	_, err = SweepAmount(0, 0)
+	assert.Equal(t, ErrNothingToSweep, err)
+}
@@ -0,0 +1,128 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KnownServer is the public key pinned for an API server
+type KnownServer struct {
+	Server      string    `json:"server"`
+	Fingerprint string    `json:"fingerprint"`
+	Subject     string    `json:"subject,omitempty"` // subject of the certificate the key was first seen in
+	Added       time.Time `json:"added"`
+}
+
+// PinMismatch is returned when an API server presents another public key than the pinned one
+type PinMismatch struct {
+	Server    string
+	Pinned    string
+	Presented string
+}
+
+func (e *PinMismatch) Error() string {
+	return fmt.Sprintf("WARNING: the public key of %s changed! Pinned: %s, presented: %s. "+
+		"Someone may be intercepting the connection, or the server may have changed its key. "+
+		"Refusing to connect. If you know that the key changed, trust the new key with the trust-server command",
+		e.Server, e.Pinned, e.Presented)
+}
+
+// KeyFingerprint returns the SSH style fingerprint of a DER encoded public key: SHA256: followed by the base64 hash
+func KeyFingerprint(publicKey []byte) string {
+	hash := sha256.Sum256(publicKey)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(hash[:])
+}
+
+// KnownServers is the file of the public keys pinned for API servers. A server's key is pinned
+// the first time the wallet connects to it.
+type KnownServers struct {
+	mu      sync.Mutex
+	path    string
+	Servers map[string]KnownServer `json:"servers"`
+}
+
+// LoadKnownServers loads the known servers file at path. A missing file results in no known servers.
+func LoadKnownServers(path string) (*KnownServers, error) {
+	k := &KnownServers{path: path, Servers: make(map[string]KnownServer)}
+	r, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening known servers file: %v", err)
+	}
+	defer r.Close()
+
+	if err = json.NewDecoder(r).Decode(k); err != nil {
+		return nil, fmt.Errorf("invalid known servers file content: %v", err)
+	}
+	if k.Servers == nil {
+		k.Servers = make(map[string]KnownServer)
+	}
+	return k, nil
+}
+
+// save writes the known servers back to their file
+func (k *KnownServers) save() error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return err
+	}
+	return WriteFileAtomic(k.path, 0600, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(k)
+	})
+}
+
+// Check checks the fingerprint presented by a server against its pinned fingerprint. The fingerprint
+// of a server which is not known yet is pinned, and added is true. It returns a *PinMismatch error
+// if the fingerprints differ.
+func (k *KnownServers) Check(server, fingerprint, subject string) (added bool, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if s, ok := k.Servers[server]; ok {
+		if s.Fingerprint != fingerprint {
+			return false, &PinMismatch{Server: server, Pinned: s.Fingerprint, Presented: fingerprint}
+		}
+		return false, nil
+	}
+	k.Servers[server] = KnownServer{Server: server, Fingerprint: fingerprint, Subject: subject, Added: time.Now()}
+	return true, k.save()
+}
+
+// Trust pins a fingerprint for a server, replacing its pinned fingerprint
+func (k *KnownServers) Trust(server, fingerprint, subject string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.Servers[server] = KnownServer{Server: server, Fingerprint: fingerprint, Subject: subject, Added: time.Now()}
+	return k.save()
+}
+
+// Forget removes the pinned fingerprint of a server, so that the next key it presents is pinned
+func (k *KnownServers) Forget(server string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.Servers[server]; !ok {
+		return fmt.Errorf("unknown server %s", server)
+	}
+	delete(k.Servers, server)
+	return k.save()
+}
+
+// List returns the known servers sorted by server
+func (k *KnownServers) List() []KnownServer {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	list := make([]KnownServer, 0, len(k.Servers))
+	for _, s := range k.Servers {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Server < list[j].Server })
+	return list
+}
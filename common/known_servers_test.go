@@ -0,0 +1,64 @@
+package common
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownServersTrustOnFirstUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cli_wallet", "known_servers.json")
+	k, err := LoadKnownServers(path)
+	require.NoError(t, err)
+
+	added, err := k.Check("api.example.org:443", "SHA256:one", "CN=api.example.org")
+	require.NoError(t, err)
+	assert.True(t, added)
+	added, err = k.Check("api.example.org:443", "SHA256:one", "CN=api.example.org")
+	require.NoError(t, err)
+	assert.False(t, added)
+
+	// the pin is kept in the file
+	k, err = LoadKnownServers(path)
+	require.NoError(t, err)
+	_, err = k.Check("api.example.org:443", "SHA256:two", "CN=api.example.org")
+	var mismatch *PinMismatch
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, "SHA256:one", mismatch.Pinned)
+	assert.Equal(t, "SHA256:two", mismatch.Presented)
+	assert.True(t, strings.HasPrefix(err.Error(), "WARNING"))
+}
+
+func TestKnownServersTrustAndForget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_servers.json")
+	k, err := LoadKnownServers(path)
+	require.NoError(t, err)
+	_, err = k.Check("b:443", "SHA256:one", "")
+	require.NoError(t, err)
+	_, err = k.Check("a:443", "SHA256:one", "")
+	require.NoError(t, err)
+
+	require.NoError(t, k.Trust("b:443", "SHA256:two", ""))
+	_, err = k.Check("b:443", "SHA256:two", "")
+	assert.NoError(t, err)
+
+	list := k.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "a:443", list[0].Server)
+	assert.Equal(t, "SHA256:two", list[1].Fingerprint)
+
+	require.NoError(t, k.Forget("a:443"))
+	assert.Error(t, k.Forget("a:443"))
+	k, err = LoadKnownServers(path)
+	require.NoError(t, err)
+	assert.Len(t, k.List(), 1)
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	// echo -n hello | sha256sum, in base64 without padding
+	assert.Equal(t, "SHA256:LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ", KeyFingerprint([]byte("hello")))
+}
@@ -0,0 +1,130 @@
+package common
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// Payment is one row of a batch payments file
+type Payment struct {
+	Line      int // row number in the file
+	Recipient gosmtypes.Address
+	Amount    uint64
+	Memo      string
+}
+
+// PaymentResult is the outcome of submitting one payment of a batch
+type PaymentResult struct {
+	Payment
+	Nonce uint64
+	TxId  string
+	State string
+}
+
+// ReadPayments reads payments from a csv file with address, amount and an optional memo on each row.
+// An optional header row is skipped. Every row is validated and all the invalid rows are reported.
+func ReadPayments(r io.Reader) ([]Payment, []error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	payments := make([]Payment, 0)
+	errs := make([]error, 0)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, append(errs, err)
+		}
+		if line == 1 && isPaymentsHeader(record) {
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			errs = append(errs, fmt.Errorf("line %d: expected address, amount and an optional memo", line))
+			continue
+		}
+		p := Payment{Line: line}
+		if p.Recipient, err = ParseAddress(record[0]); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", line, err))
+			continue
+		}
+		if p.Amount, err = ParseAmount(record[1]); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", line, err))
+			continue
+		}
+		if p.Amount == 0 {
+			errs = append(errs, fmt.Errorf("line %d: amount must be greater than zero", line))
+			continue
+		}
+		if len(record) == 3 {
+			p.Memo = strings.TrimSpace(record[2])
+		}
+		payments = append(payments, p)
+	}
+	if len(payments) == 0 && len(errs) == 0 {
+		errs = append(errs, errors.New("no payments found"))
+	}
+	return payments, errs
+}
+
+func isPaymentsHeader(record []string) bool {
+	return len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "address")
+}
+
+// PaymentsTotal returns the sum of the payments amounts and fails if it overflows
+func PaymentsTotal(payments []Payment) (uint64, error) {
+	total := uint64(0)
+	for _, p := range payments {
+		if total+p.Amount < total {
+			return 0, errors.New("total amount overflows")
+		}
+		total += p.Amount
+	}
+	return total, nil
+}
+
+// PaymentsCost returns the sum of the payments amounts, the fees of the payments which each pay fee,
+// and their sum. It fails if any of them overflows.
+func PaymentsCost(payments []Payment, fee uint64) (total, fees, cost uint64, err error) {
+	if total, err = PaymentsTotal(payments); err != nil {
+		return 0, 0, 0, err
+	}
+	hi, fees := bits.Mul64(fee, uint64(len(payments)))
+	if hi != 0 {
+		return 0, 0, 0, errors.New("total fees overflow")
+	}
+	cost, carry := bits.Add64(total, fees, 0)
+	if carry != 0 {
+		return 0, 0, 0, errors.New("total cost overflows")
+	}
+	return total, fees, cost, nil
+}
+
+// WritePaymentResults writes the outcome of a batch of payments as csv
+func WritePaymentResults(w io.Writer, results []PaymentResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"address", "amount", "memo", "nonce", "txid", "state"}); err != nil {
+		return err
+	}
+	for _, r := range results {
+		nonce := ""
+		if r.TxId != "" {
+			nonce = strconv.FormatUint(r.Nonce, 10)
+		}
+		err := writer.Write([]string{r.Recipient.String(), strconv.FormatUint(r.Amount, 10), r.Memo, nonce, r.TxId, r.State})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPayments(t *testing.T) {
+	file := "address,amount,memo\n" +
+		checksummedAddress + ",100\n" +
+		checksummedAddress + ",1.5SMH,pool payout\n"
+	payments, errs := ReadPayments(strings.NewReader(file))
+	require.Empty(t, errs)
+	require.Len(t, payments, 2)
+	assert.Equal(t, 2, payments[0].Line)
+	assert.Equal(t, uint64(100), payments[0].Amount)
+	assert.Equal(t, "pool payout", payments[1].Memo)
+
+	total, err := PaymentsTotal(payments)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1500000000100), total)
+}
+
+func TestPaymentsCost(t *testing.T) {
+	payments := []Payment{{Amount: 100}, {Amount: 200}}
+	total, fees, cost, err := PaymentsCost(payments, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{300, 10, 310}, []uint64{total, fees, cost})
+
+	_, _, _, err = PaymentsCost(payments, math.MaxUint64/2+1)
+	assert.Error(t, err, "the fees overflow")
+	_, _, _, err = PaymentsCost(payments, math.MaxUint64/2-100)
+	assert.Error(t, err, "the total cost overflows")
+}
+
+func TestReadPaymentsInvalidRows(t *testing.T) {
+	file := checksummedAddress + ",100\n" +
+		"0x1234,100\n" +
+		checksummedAddress + ",lots\n" +
+		checksummedAddress + ",0\n" +
+		checksummedAddress + "\n"
+	payments, errs := ReadPayments(strings.NewReader(file))
+	assert.Len(t, payments, 1)
+	require.Len(t, errs, 4)
+	assert.Contains(t, errs[0].Error(), "line 2")
+	assert.Contains(t, errs[3].Error(), "line 5")
+
+	_, errs = ReadPayments(strings.NewReader(""))
+	assert.Len(t, errs, 1)
+}
+
+func TestWritePaymentResults(t *testing.T) {
+	addr, _ := ParseAddress(checksummedAddress)
+	var buf bytes.Buffer
+	err := WritePaymentResults(&buf, []PaymentResult{
+		{Payment: Payment{Recipient: addr, Amount: 5, Memo: "a"}, Nonce: 7, TxId: "0xab", State: "Submitted"},
+		{Payment: Payment{Recipient: addr, Amount: 6}, State: "Not submitted"},
+	})
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, checksummedAddress+",5,a,7,0xab,Submitted", lines[1])
+	assert.Equal(t, checksummedAddress+",6,,,,Not submitted", lines[2])
+}
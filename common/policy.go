@@ -0,0 +1,240 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Spending policy rules
+const (
+	RuleTxLimit      = "per-transaction limit"
+	RuleDailyLimit   = "daily limit"
+	RuleAllowlist    = "recipient allowlist"
+	RuleBlockedHours = "blocked hours"
+	RuleApproval     = "second password"
+)
+
+// number of PBKDF2 iterations used to hash the approval password
+const approvalIterations = 100000
+
+// SpendRecord is an amount sent from an account, kept in the wallet for the daily limit
+type SpendRecord struct {
+	Time   time.Time `json:"time"`
+	Amount uint64    `json:"amount"`
+}
+
+// how long spend records are kept
+const spendRecordsAge = 48 * time.Hour
+
+// AddSpendRecord appends r to the spend records of an account, dropping the records too old to count
+func AddSpendRecord(records []SpendRecord, r SpendRecord) []SpendRecord {
+	kept := make([]SpendRecord, 0, len(records)+1)
+	for _, old := range records {
+		if r.Time.Sub(old.Time) < spendRecordsAge {
+			kept = append(kept, old)
+		}
+	}
+	return append(kept, r)
+}
+
+// SpentSince returns the total amount of the spend records since t
+func SpentSince(records []SpendRecord, t time.Time) uint64 {
+	total := uint64(0)
+	for _, r := range records {
+		if !r.Time.Before(t) {
+			total += r.Amount
+		}
+	}
+	return total
+}
+
+// SpendingPolicy restricts the transfers of an account. Zero values don't restrict.
+type SpendingPolicy struct {
+	Account           string   `json:"account"`
+	TxLimit           uint64   `json:"txLimit,omitempty"`
+	DailyLimit        uint64   `json:"dailyLimit,omitempty"`
+	Allowlist         []string `json:"allowlist,omitempty"`
+	BlockedHours      []int    `json:"blockedHours,omitempty"` // local hours of the day in which transfers are blocked
+	ApprovalThreshold uint64   `json:"approvalThreshold,omitempty"`
+	ApprovalSalt      string   `json:"approvalSalt,omitempty"`
+	ApprovalHash      string   `json:"approvalHash,omitempty"`
+}
+
+// PolicyViolation is returned when a transfer breaks a rule of a spending policy
+type PolicyViolation struct {
+	Rule   string
+	Reason string
+}
+
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("blocked by the %s: %s", v.Rule, v.Reason)
+}
+
+// SetApprovalPassword sets the second password required for transfers above the approval threshold
+func (p *SpendingPolicy) SetApprovalPassword(password string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	p.ApprovalSalt = hex.EncodeToString(salt)
+	p.ApprovalHash = hex.EncodeToString(approvalKey(password, salt))
+	return nil
+}
+
+// HasApprovalPassword returns true iff a second password is set
+func (p *SpendingPolicy) HasApprovalPassword() bool {
+	return p.ApprovalHash != ""
+}
+
+// CheckApprovalPassword returns true iff password is the second password of the policy
+func (p *SpendingPolicy) CheckApprovalPassword(password string) bool {
+	salt, err := hex.DecodeString(p.ApprovalSalt)
+	if err != nil || !p.HasApprovalPassword() {
+		return false
+	}
+	hash, err := hex.DecodeString(p.ApprovalHash)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hash, approvalKey(password, salt)) == 1
+}
+
+func approvalKey(password string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(password), salt, approvalIterations, 32, sha512.New)
+}
+
+// NeedsApproval returns true iff a transfer of amount requires the second password
+func (p *SpendingPolicy) NeedsApproval(amount uint64) bool {
+	return p.HasApprovalPassword() && amount > p.ApprovalThreshold
+}
+
+// Check returns a *PolicyViolation for the first rule a transfer breaks, or nil if it is allowed.
+// The daily limit counts the fees. spentToday is the amount already sent by the account today, fees included,
+// and approved tells whether the second password was given for this transfer.
+func (p *SpendingPolicy) Check(recipient gosmtypes.Address, amount, fee, spentToday uint64, now time.Time, approved bool) error {
+	hour := now.Hour()
+	for _, h := range p.BlockedHours {
+		if h == hour {
+			return &PolicyViolation{RuleBlockedHours, fmt.Sprintf("transfers are not allowed between %02d:00 and %02d:59", hour, hour)}
+		}
+	}
+	if len(p.Allowlist) > 0 {
+		allowed := false
+		for _, a := range p.Allowlist {
+			if addr, err := ParseAddress(a); err == nil && addr == recipient {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &PolicyViolation{RuleAllowlist, fmt.Sprintf("%s is not an allowed recipient", recipient.String())}
+		}
+	}
+	if p.TxLimit > 0 && amount > p.TxLimit {
+		return &PolicyViolation{RuleTxLimit, fmt.Sprintf("amount %d is above the limit of %d", amount, p.TxLimit)}
+	}
+	if p.DailyLimit > 0 {
+		cost, costCarry := bits.Add64(amount, fee, 0)
+		total, totalCarry := bits.Add64(spentToday, cost, 0)
+		if costCarry != 0 || totalCarry != 0 || total > p.DailyLimit {
+			return &PolicyViolation{RuleDailyLimit, fmt.Sprintf("%d already sent today, %d more with the fee is above the limit of %d", spentToday, cost, p.DailyLimit)}
+		}
+	}
+	if p.NeedsApproval(amount) && !approved {
+		return &PolicyViolation{RuleApproval, fmt.Sprintf("amount %d is above %d and needs the second password", amount, p.ApprovalThreshold)}
+	}
+	return nil
+}
+
+// Describe returns the rules of the policy in human readable form
+func (p *SpendingPolicy) Describe() []string {
+	rules := make([]string, 0)
+	if p.TxLimit > 0 {
+		rules = append(rules, fmt.Sprintf("Per-transaction limit: %d", p.TxLimit))
+	}
+	if p.DailyLimit > 0 {
+		rules = append(rules, fmt.Sprintf("Daily limit: %d", p.DailyLimit))
+	}
+	if len(p.Allowlist) > 0 {
+		rules = append(rules, "Allowed recipients: "+strings.Join(p.Allowlist, ", "))
+	}
+	if len(p.BlockedHours) > 0 {
+		rules = append(rules, "Blocked hours: "+FormatHours(p.BlockedHours))
+	}
+	if p.HasApprovalPassword() {
+		rules = append(rules, fmt.Sprintf("Second password above: %d", p.ApprovalThreshold))
+	}
+	return rules
+}
+
+// ParseHours parses a comma separated list of hours and hour ranges such as "22-6,13".
+// A range which ends before it starts wraps around midnight.
+func ParseHours(s string) ([]int, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := parseHour(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, err = parseHour(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		for h := from; ; h = (h + 1) % 24 {
+			set[h] = true
+			if h == to {
+				break
+			}
+		}
+	}
+	hours := make([]int, 0, len(set))
+	for h := range set {
+		hours = append(hours, h)
+	}
+	sort.Ints(hours)
+	return hours, nil
+}
+
+func parseHour(s string) (int, error) {
+	h, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid hour %s. Use 0 to 23", s)
+	}
+	return h, nil
+}
+
+// FormatHours formats sorted hours as a list of ranges
+func FormatHours(hours []int) string {
+	parts := make([]string, 0)
+	for i := 0; i < len(hours); {
+		j := i
+		for j+1 < len(hours) && hours[j+1] == hours[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(hours[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", hours[i], hours[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
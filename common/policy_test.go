@@ -0,0 +1,71 @@
+package common
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func violatedRule(err error) string {
+	if v, ok := err.(*PolicyViolation); ok {
+		return v.Rule
+	}
+	return ""
+}
+
+func TestSpendingPolicyCheck(t *testing.T) {
+	allowed, _ := ParseAddress(checksummedAddress)
+	other := gosmtypes.BytesToAddress([]byte{1})
+	noon := time.Date(2020, 11, 1, 12, 30, 0, 0, time.Local)
+
+	p := SpendingPolicy{TxLimit: 100, DailyLimit: 150, Allowlist: []string{checksummedAddress}, BlockedHours: []int{22, 23}}
+	assert.NoError(t, p.Check(allowed, 100, 0, 50, noon, false))
+	assert.Equal(t, RuleTxLimit, violatedRule(p.Check(allowed, 101, 0, 0, noon, false)))
+	assert.Equal(t, RuleDailyLimit, violatedRule(p.Check(allowed, 60, 0, 100, noon, false)))
+	assert.Equal(t, RuleDailyLimit, violatedRule(p.Check(allowed, 100, 1, 50, noon, false)))
+	assert.Equal(t, RuleDailyLimit, violatedRule(p.Check(allowed, 100, math.MaxUint64, 0, noon, false)))
+	assert.Equal(t, RuleAllowlist, violatedRule(p.Check(other, 1, 0, 0, noon, false)))
+	assert.Equal(t, RuleBlockedHours, violatedRule(p.Check(allowed, 1, 0, 0, noon.Add(10*time.Hour), false)))
+
+	require.NoError(t, p.SetApprovalPassword("second"))
+	p.ApprovalThreshold = 10
+	assert.Equal(t, RuleApproval, violatedRule(p.Check(allowed, 11, 0, 0, noon, false)))
+	assert.NoError(t, p.Check(allowed, 11, 0, 0, noon, true))
+	assert.NoError(t, p.Check(allowed, 10, 0, 0, noon, false))
+	assert.True(t, p.CheckApprovalPassword("second"))
+	assert.False(t, p.CheckApprovalPassword("first"))
+
+	assert.NoError(t, (&SpendingPolicy{}).Check(other, 1<<60, 0, 1<<60, noon, false))
+	assert.Len(t, p.Describe(), 5)
+}
+
+func TestParseHours(t *testing.T) {
+	hours, err := ParseHours("22-2, 13")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 13, 22, 23}, hours)
+	assert.Equal(t, "0-2,13,22-23", FormatHours(hours))
+
+	hours, err = ParseHours("")
+	require.NoError(t, err)
+	assert.Empty(t, hours)
+
+	_, err = ParseHours("24")
+	assert.Error(t, err)
+	_, err = ParseHours("1-x")
+	assert.Error(t, err)
+}
+
+func TestSpendRecords(t *testing.T) {
+	now := time.Now()
+	records := AddSpendRecord(nil, SpendRecord{Time: now.Add(-50 * time.Hour), Amount: 100})
+	records = AddSpendRecord(records, SpendRecord{Time: now.Add(-3 * time.Hour), Amount: 5})
+	records = AddSpendRecord(records, SpendRecord{Time: now, Amount: 7})
+	assert.Len(t, records, 2, "records older than two days are dropped")
+
+	assert.Equal(t, uint64(12), SpentSince(records, now.Add(-time.Hour*4)))
+	assert.Equal(t, uint64(7), SpentSince(records, now.Add(-time.Hour)))
+}
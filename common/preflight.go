@@ -0,0 +1,67 @@
+package common
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// TransferPreflight holds what is known about a transfer and its sender before it is submitted
+type TransferPreflight struct {
+	Amount           uint64
+	Fee              uint64
+	Nonce            uint64
+	ProjectedBalance uint64               // sender balance after the transactions known to the node
+	ProjectedNonce   uint64               // next sender nonce after the transactions known to the node
+	Pending          []PendingTransaction // sender transactions in the local journal
+	RecipientIsOwn   bool                 // recipient is an account of the wallet
+	RecipientSeen    bool                 // recipient has a balance or transactions on the mesh
+}
+
+// PreflightResult is the outcome of the checks of a transfer
+type PreflightResult struct {
+	Available    uint64 // balance left for the transfer after pending transactions
+	Insufficient bool   // the transfer can't be paid for
+	Warnings     []string
+}
+
+// Check simulates the transfer against the projected state of the sender.
+// Pending journal transactions the node doesn't know about yet are taken out of the balance.
+func (p TransferPreflight) Check() PreflightResult {
+	res := PreflightResult{Available: p.ProjectedBalance}
+	for _, tx := range p.Pending {
+		if tx.Dropped || tx.Nonce < p.ProjectedNonce {
+			continue
+		}
+		cost, carry := bits.Add64(tx.Amount, tx.Fee, 0)
+		if carry != 0 || cost > res.Available {
+			res.Available = 0
+		} else {
+			res.Available -= cost
+		}
+	}
+
+	// the node requires the balance to stay above zero
+	if cost, carry := bits.Add64(p.Amount, p.Fee, 0); carry != 0 {
+		res.Insufficient = true
+		res.Warnings = append(res.Warnings, fmt.Sprintf("Insufficient funds: the amount %d plus the fee %d of the transfer overflow", p.Amount, p.Fee))
+	} else if cost >= res.Available {
+		res.Insufficient = true
+		res.Warnings = append(res.Warnings, fmt.Sprintf("Insufficient funds: the transfer costs %d but only %d is available", cost, res.Available))
+	}
+	if p.Nonce < p.ProjectedNonce {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("Nonce %d is already used, the next nonce is %d", p.Nonce, p.ProjectedNonce))
+	} else {
+		for _, tx := range p.Pending {
+			if !tx.Dropped && tx.Nonce == p.Nonce {
+				res.Warnings = append(res.Warnings, fmt.Sprintf("Nonce %d conflicts with pending transaction 0x%s", p.Nonce, tx.Id))
+			}
+		}
+	}
+	if p.RecipientIsOwn {
+		res.Warnings = append(res.Warnings, "The recipient is an account of this wallet")
+	}
+	if !p.RecipientSeen {
+		res.Warnings = append(res.Warnings, "The recipient has never been seen on the mesh. Please check the address")
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferPreflight(t *testing.T) {
+	p := TransferPreflight{Amount: 50, Fee: 1, Nonce: 3, ProjectedBalance: 100, ProjectedNonce: 2, RecipientSeen: true,
+		Pending: []PendingTransaction{
+			{Id: "01", Nonce: 1, Amount: 1000}, // known to the node
+			{Id: "02", Nonce: 2, Amount: 30, Fee: 1},
+			{Id: "03", Nonce: 3, Amount: 1000, Dropped: true},
+		}}
+	res := p.Check()
+	assert.Equal(t, uint64(69), res.Available)
+	assert.False(t, res.Insufficient)
+	assert.Empty(t, res.Warnings)
+
+	p.Amount = 68
+	res = p.Check()
+	assert.True(t, res.Insufficient)
+	assert.Len(t, res.Warnings, 1)
+
+	p.Amount, p.Nonce, p.RecipientIsOwn, p.RecipientSeen = 1, 2, true, false
+	res = p.Check()
+	assert.False(t, res.Insufficient)
+	assert.Len(t, res.Warnings, 3)
+
+	p.Nonce = 1
+	assert.Len(t, p.Check().Warnings, 3)
+
+	// amounts which overflow are never affordable
+	p.Amount, p.Nonce, p.RecipientIsOwn, p.RecipientSeen = math.MaxUint64, 3, false, true
+	res = p.Check()
+	assert.True(t, res.Insufficient)
+	assert.Len(t, res.Warnings, 1)
+
+	p.Amount = 1
+	p.Pending = append(p.Pending, PendingTransaction{Id: "04", Nonce: 3, Amount: math.MaxUint64, Fee: 1})
+	res = p.Check()
+	assert.Equal(t, uint64(0), res.Available)
+	assert.True(t, res.Insufficient)
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"strings"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+)
+
+// TxStateName returns the short name of a transaction state, e.g. mempool
+func TxStateName(s apitypes.TransactionState_TransactionState) string {
+	return strings.ToLower(strings.TrimPrefix(s.String(), "TRANSACTION_STATE_"))
+}
+
+var txResultText = map[apitypes.TransactionReceipt_TransactionResult]string{
+	apitypes.TransactionReceipt_TRANSACTION_RESULT_UNSPECIFIED:        "Unspecified result",
+	apitypes.TransactionReceipt_TRANSACTION_RESULT_EXECUTED:           "Executed",
+	apitypes.TransactionReceipt_TRANSACTION_RESULT_BAD_COUNTER:        "Failed: unexpected nonce",
+	apitypes.TransactionReceipt_TRANSACTION_RESULT_RUNTIME_EXCEPTION:  "Failed: runtime exception",
+	apitypes.TransactionReceipt_TRANSACTION_RESULT_INSUFFICIENT_GAS:   "Failed: out of gas",
+	apitypes.TransactionReceipt_TRANSACTION_RESULT_INSUFFICIENT_FUNDS: "Failed: insufficient funds",
+}
+
+// TxResultText returns a readable description of a transaction receipt result
+func TxResultText(result apitypes.TransactionReceipt_TransactionResult) string {
+	if s, ok := txResultText[result]; ok {
+		return s
+	}
+	return result.String()
+}
+
+// TxFailed returns true iff the receipt result is a failure
+func TxFailed(result apitypes.TransactionReceipt_TransactionResult) bool {
+	return result != apitypes.TransactionReceipt_TRANSACTION_RESULT_UNSPECIFIED &&
+		result != apitypes.TransactionReceipt_TRANSACTION_RESULT_EXECUTED
+}
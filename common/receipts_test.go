@@ -0,0 +1,23 @@
+package common
+
+import (
+	"testing"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxResultText(t *testing.T) {
+	assert.Equal(t, "Executed", TxResultText(apitypes.TransactionReceipt_TRANSACTION_RESULT_EXECUTED))
+	assert.Equal(t, "Failed: insufficient funds", TxResultText(apitypes.TransactionReceipt_TRANSACTION_RESULT_INSUFFICIENT_FUNDS))
+	assert.Equal(t, "42", TxResultText(42))
+
+	assert.False(t, TxFailed(apitypes.TransactionReceipt_TRANSACTION_RESULT_EXECUTED))
+	assert.False(t, TxFailed(apitypes.TransactionReceipt_TRANSACTION_RESULT_UNSPECIFIED))
+	assert.True(t, TxFailed(apitypes.TransactionReceipt_TRANSACTION_RESULT_BAD_COUNTER))
+}
+
+func TestTxStateName(t *testing.T) {
+	assert.Equal(t, "mempool", TxStateName(apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL))
+	assert.Equal(t, "insufficient_funds", TxStateName(apitypes.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS))
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Kinds of payment schedules
+const (
+	ScheduleEveryLayers = "layers" // every given number of layers
+	ScheduleEveryEpoch  = "epoch"  // at the first layer of every epoch
+	ScheduleCron        = "cron"   // at wall clock times given by a cron expression
+)
+
+// ScheduledPayment is a recurring payment from one of the wallet accounts.
+// Scheduled payments are stored in the encrypted part of the wallet.
+type ScheduledPayment struct {
+	Id        string    `json:"id"`
+	Account   string    `json:"account"`
+	Recipient string    `json:"recipient"`
+	Amount    uint64    `json:"amount"`
+	Fee       uint64    `json:"fee"`
+	Kind      string    `json:"kind"`
+	Layers    uint32    `json:"layers,omitempty"`
+	Cron      string    `json:"cron,omitempty"`
+	LastLayer uint32    `json:"lastLayer"`
+	LastRun   time.Time `json:"lastRun"`
+}
+
+// Validate checks the schedule definition
+func (s *ScheduledPayment) Validate() error {
+	if _, err := ParseAddress(s.Account); err != nil {
+		return fmt.Errorf("invalid source account: %v", err)
+	}
+	if _, err := ParseAddress(s.Recipient); err != nil {
+		return fmt.Errorf("invalid recipient: %v", err)
+	}
+	if s.Amount == 0 {
+		return fmt.Errorf("amount must be greater than zero")
+	}
+	switch s.Kind {
+	case ScheduleEveryLayers:
+		if s.Layers == 0 {
+			return fmt.Errorf("layer interval must be greater than zero")
+		}
+	case ScheduleEveryEpoch:
+	case ScheduleCron:
+		if _, err := ParseCron(s.Cron); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown schedule kind: %s", s.Kind)
+	}
+	return nil
+}
+
+// Due returns true iff the payment should run at the current layer and time
+func (s *ScheduledPayment) Due(currentLayer uint32, layersPerEpoch uint64, now time.Time) bool {
+	switch s.Kind {
+	case ScheduleEveryLayers:
+		return currentLayer >= s.LastLayer+s.Layers
+	case ScheduleEveryEpoch:
+		if layersPerEpoch == 0 {
+			return false
+		}
+		return uint64(currentLayer)/layersPerEpoch > uint64(s.LastLayer)/layersPerEpoch
+	case ScheduleCron:
+		spec, err := ParseCron(s.Cron)
+		if err != nil {
+			return false
+		}
+		next := spec.Next(s.LastRun)
+		return !next.IsZero() && !next.After(now)
+	}
+	return false
+}
+
+// Describe returns a human readable description of when the payment runs
+func (s *ScheduledPayment) Describe() string {
+	switch s.Kind {
+	case ScheduleEveryLayers:
+		return fmt.Sprintf("every %d layers", s.Layers)
+	case ScheduleEveryEpoch:
+		return "every epoch"
+	case ScheduleCron:
+		return fmt.Sprintf("cron %q", s.Cron)
+	}
+	return s.Kind
+}
+
+// ScheduleExecution is an entry in the log of scheduled payments executions
+type ScheduleExecution struct {
+	ScheduleId string    `json:"scheduleId"`
+	Time       time.Time `json:"time"`
+	Layer      uint32    `json:"layer"`
+	Recipient  string    `json:"recipient"`
+	Amount     uint64    `json:"amount"`
+	TxId       string    `json:"txId,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// AppendScheduleLog appends an execution to the log file at path, one json object per line
+func AppendScheduleLog(path string, e ScheduleExecution) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(e)
+}
+
+// ReadScheduleLog reads all the executions in the log file at path
+func ReadScheduleLog(path string) ([]ScheduleExecution, error) {
+	executions := make([]ScheduleExecution, 0)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return executions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e ScheduleExecution
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid schedule log entry: %v", err)
+		}
+		executions = append(executions, e)
+	}
+	return executions, scanner.Err()
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledPaymentDue(t *testing.T) {
+	s := ScheduledPayment{Kind: ScheduleEveryLayers, Layers: 10, LastLayer: 100}
+	assert.False(t, s.Due(109, 50, time.Now()))
+	assert.True(t, s.Due(110, 50, time.Now()))
+
+	s = ScheduledPayment{Kind: ScheduleEveryEpoch, LastLayer: 120}
+	assert.False(t, s.Due(149, 50, time.Now()))
+	assert.True(t, s.Due(150, 50, time.Now()))
+	assert.False(t, s.Due(150, 0, time.Now()))
+
+	last := time.Date(2021, 1, 25, 9, 0, 0, 0, time.UTC)
+	s = ScheduledPayment{Kind: ScheduleCron, Cron: "0 * * * *", LastRun: last}
+	assert.False(t, s.Due(0, 0, last.Add(59*time.Minute)))
+	assert.True(t, s.Due(0, 0, last.Add(time.Hour)))
+}
+
+func TestScheduledPaymentValidate(t *testing.T) {
+	s := ScheduledPayment{Account: checksummedAddress, Recipient: checksummedAddress, Amount: 1, Kind: ScheduleEveryEpoch}
+	assert.NoError(t, s.Validate())
+
+	invalid := s
+	invalid.Kind = ScheduleEveryLayers
+	assert.Error(t, invalid.Validate())
+	invalid = s
+	invalid.Kind = ScheduleCron
+	invalid.Cron = "every day"
+	assert.Error(t, invalid.Validate())
+	invalid = s
+	invalid.Recipient = "0x12"
+	assert.Error(t, invalid.Validate())
+}
+
+func TestScheduleLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	executions, err := ReadScheduleLog(path)
+	require.NoError(t, err)
+	assert.Empty(t, executions)
+
+	require.NoError(t, AppendScheduleLog(path, ScheduleExecution{ScheduleId: "a", TxId: "0x01"}))
+	require.NoError(t, AppendScheduleLog(path, ScheduleExecution{ScheduleId: "b", Error: "failed"}))
+	executions, err = ReadScheduleLog(path)
+	require.NoError(t, err)
+	require.Len(t, executions, 2)
+	assert.Equal(t, "0x01", executions[0].TxId)
+	assert.Equal(t, "failed", executions[1].Error)
+}
@@ -0,0 +1,113 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Script header options
+const (
+	ScriptYes       = "--yes"        // answer yes to all confirmations
+	ScriptKeepGoing = "--keep-going" // run the remaining commands after a command fails
+)
+
+// ScriptCommand is one command of a script, with its variables expanded
+type ScriptCommand struct {
+	Line int // line number in the file
+	Text string
+}
+
+// Script is a sequence of REPL commands read from a file
+type Script struct {
+	AssumeYes bool
+	KeepGoing bool
+	Commands  []ScriptCommand
+}
+
+var (
+	scriptVarRef  = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	scriptVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ParseScript reads a script. Blank lines and lines starting with # are skipped.
+// Comment lines at the top of the file starting with #! are the header and hold the script options.
+// "let NAME = VALUE" defines a variable which later lines use as ${NAME}. Names which are not defined
+// are looked up with lookupEnv, usually os.LookupEnv.
+func ParseScript(r io.Reader, lookupEnv func(string) (string, bool)) (*Script, error) {
+	s := &Script{Commands: make([]ScriptCommand, 0)}
+	vars := make(map[string]string)
+	header := true
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "#!") && header {
+			if err := s.setOptions(strings.Fields(text[2:])); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		header = false
+
+		var err error
+		if text, err = expandScriptVars(text, vars, lookupEnv); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if strings.HasPrefix(text, "let ") {
+			parts := strings.SplitN(strings.TrimPrefix(text, "let "), "=", 2)
+			name := strings.TrimSpace(parts[0])
+			if len(parts) != 2 || !scriptVarName.MatchString(name) {
+				return nil, fmt.Errorf("line %d: invalid variable definition. Use let NAME = VALUE", line)
+			}
+			vars[name] = strings.TrimSpace(parts[1])
+			continue
+		}
+		s.Commands = append(s.Commands, ScriptCommand{Line: line, Text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Script) setOptions(options []string) error {
+	for _, o := range options {
+		switch o {
+		case ScriptYes:
+			s.AssumeYes = true
+		case ScriptKeepGoing:
+			s.KeepGoing = true
+		default:
+			return fmt.Errorf("unknown script option %s", o)
+		}
+	}
+	return nil
+}
+
+func expandScriptVars(text string, vars map[string]string, lookupEnv func(string) (string, bool)) (string, error) {
+	var err error
+	expanded := scriptVarRef.ReplaceAllStringFunc(text, func(ref string) string {
+		name := scriptVarRef.FindStringSubmatch(ref)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		if lookupEnv != nil {
+			if v, ok := lookupEnv(name); ok {
+				return v
+			}
+		}
+		if err == nil {
+			err = fmt.Errorf("undefined variable %s", name)
+		}
+		return ref
+	})
+	return expanded, err
+}
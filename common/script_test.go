@@ -0,0 +1,50 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScript(t *testing.T) {
+	env := map[string]string{"AMOUNT": "2.5SMH"}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	file := "#! --yes\n" +
+		"# monthly payout\n" +
+		"\n" +
+		"let to = " + checksummedAddress + "\n" +
+		"set main\n" +
+		"send-coin ${to} ${AMOUNT} --fee 2\n" +
+		"#! --keep-going\n"
+	s, err := ParseScript(strings.NewReader(file), lookupEnv)
+	require.NoError(t, err)
+	assert.True(t, s.AssumeYes)
+	assert.False(t, s.KeepGoing, "options are only read from the header")
+	assert.Equal(t, []ScriptCommand{
+		{Line: 5, Text: "set main"},
+		{Line: 6, Text: "send-coin " + checksummedAddress + " 2.5SMH --fee 2"},
+	}, s.Commands)
+
+	s, err = ParseScript(strings.NewReader("info\n"), nil)
+	require.NoError(t, err)
+	assert.False(t, s.AssumeYes)
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	for _, file := range []string{
+		"#! --force\ninfo\n",
+		"info\nsend-coin ${to} 1\n",
+		"let 1x = 2\n",
+		"let x\n",
+	} {
+		_, err := ParseScript(strings.NewReader(file), nil)
+		assert.Error(t, err, file)
+	}
+	_, err := ParseScript(strings.NewReader("info\nsend-coin ${to} 1\n"), nil)
+	assert.EqualError(t, err, "line 2: undefined variable to")
+}
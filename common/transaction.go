@@ -16,3 +16,20 @@ type SerializableSignedTransaction struct {
 	InnerSerializableSignedTransaction
 	Signature [64]byte
 }
+
+// InnerSerializableContractTransaction is the signed part of an app call, app spawn or template deployment
+type InnerSerializableContractTransaction struct {
+	Type         uint32 // the SmartContractTransaction_TransactionType of the api
+	AccountNonce uint64
+	Account      types.Address // the app called or the template spawned, empty when deploying a template
+	GasLimit     uint64
+	Price        uint64
+	Amount       uint64
+	Data         []byte // packed binary arguments, starting with the function selector of an app call
+}
+
+// SerializableSignedContractTransaction is a signed app call, app spawn or template deployment
+type SerializableSignedContractTransaction struct {
+	InnerSerializableContractTransaction
+	Signature [64]byte
+}
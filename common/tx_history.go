@@ -0,0 +1,168 @@
+package common
+
+import (
+	"sort"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// Transaction directions relative to an account
+const (
+	TxIn   = "in"
+	TxOut  = "out"
+	TxSelf = "self"
+)
+
+// Transaction history sort orders
+const (
+	SortByLayer  = "layer"
+	SortByAmount = "amount"
+)
+
+// TxHistoryEntry is a mesh transaction seen from one account
+type TxHistoryEntry struct {
+	Index        int // position in layer order, starting from 1 for the oldest transaction
+	Tx           *apitypes.Transaction
+	Direction    string
+	Counterparty gosmtypes.Address
+	Amount       uint64
+	Fee          uint64
+	Balance      uint64 // account balance after the transaction
+}
+
+// TxFilter selects transactions from a history. Zero values don't filter.
+type TxFilter struct {
+	Direction    string
+	Counterparty *gosmtypes.Address
+	MinAmount    uint64
+	MaxAmount    uint64
+	Ids          map[string]bool // when not nil, only transactions with these ids
+}
+
+// Credit is an amount credited to an account outside of its transactions, such as a reward
+type Credit struct {
+	After  int // number of transactions of the history before the credit
+	Amount uint64
+}
+
+// LayerCredits places the amounts credited at the end of layers, such as rewards, in a history whose
+// transactions are in txLayers, in layer order
+func LayerCredits(txLayers []uint32, amounts map[uint32]uint64) []Credit {
+	credits := make([]Credit, 0, len(amounts))
+	for layer, amount := range amounts {
+		after := sort.Search(len(txLayers), func(i int) bool { return txLayers[i] > layer })
+		credits = append(credits, Credit{After: after, Amount: amount})
+	}
+	return credits
+}
+
+// TxHistory returns the history of an account from its mesh transactions in layer order.
+// The running balance is derived backwards from the current balance and the credits.
+func TxHistory(address gosmtypes.Address, txs []*apitypes.Transaction, currentBalance uint64, credits []Credit) []TxHistoryEntry {
+	entries := make([]TxHistoryEntry, len(txs))
+	for i, tx := range txs {
+		e := TxHistoryEntry{Index: i + 1, Tx: tx, Amount: tx.Amount.GetValue(), Fee: tx.GasOffered.GetGasPrice()}
+		sender := gosmtypes.BytesToAddress(tx.Sender.GetAddress())
+		receiver := gosmtypes.Address{}
+		if ct := tx.GetCoinTransfer(); ct != nil {
+			receiver = gosmtypes.BytesToAddress(ct.Receiver.GetAddress())
+		} else if sct := tx.GetSmartContract(); sct != nil {
+			receiver = gosmtypes.BytesToAddress(sct.AccountId.GetAddress())
+		}
+		switch {
+		case sender == address && receiver == address:
+			e.Direction, e.Counterparty = TxSelf, address
+		case sender == address:
+			e.Direction, e.Counterparty = TxOut, receiver
+		default:
+			e.Direction, e.Counterparty = TxIn, sender
+		}
+		entries[i] = e
+	}
+
+	credited := make([]uint64, len(entries)+1)
+	for _, c := range credits {
+		if c.After >= 0 && c.After <= len(entries) {
+			credited[c.After] += c.Amount
+		}
+	}
+	balance := currentBalance
+	for i := len(entries) - 1; i >= 0; i-- {
+		if credited[i+1] > balance {
+			balance = 0
+		} else {
+			balance -= credited[i+1]
+		}
+		entries[i].Balance = balance
+		switch entries[i].Direction {
+		case TxIn:
+			if entries[i].Amount > balance {
+				// the node doesn't report all the credits
+				balance = 0
+				continue
+			}
+			balance -= entries[i].Amount
+		case TxOut:
+			balance += entries[i].Amount + entries[i].Fee
+		case TxSelf:
+			balance += entries[i].Fee
+		}
+	}
+	return entries
+}
+
+// Filter returns the entries matching the filter
+func (f TxFilter) Filter(entries []TxHistoryEntry) []TxHistoryEntry {
+	res := make([]TxHistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		if f.Direction != "" && e.Direction != f.Direction && e.Direction != TxSelf {
+			continue
+		}
+		if f.Counterparty != nil && e.Counterparty != *f.Counterparty {
+			continue
+		}
+		if e.Amount < f.MinAmount || (f.MaxAmount > 0 && e.Amount > f.MaxAmount) {
+			continue
+		}
+		if f.Ids != nil && !f.Ids[string(e.Tx.Id.GetId())] {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+// SortTxHistory sorts entries by layer or amount. Entries are in layer order to start with.
+func SortTxHistory(entries []TxHistoryEntry, by string, descending bool) {
+	less := func(i, j int) bool { return entries[i].Index < entries[j].Index }
+	if by == SortByAmount {
+		less = func(i, j int) bool {
+			if entries[i].Amount == entries[j].Amount {
+				return entries[i].Index < entries[j].Index
+			}
+			return entries[i].Amount < entries[j].Amount
+		}
+	}
+	if descending {
+		sort.SliceStable(entries, func(i, j int) bool { return less(j, i) })
+	} else {
+		sort.SliceStable(entries, less)
+	}
+}
+
+// PageTxHistory returns page number page (starting from 1) of pageSize entries
+func PageTxHistory(entries []TxHistoryEntry, page, pageSize int) []TxHistoryEntry {
+	if page < 1 || pageSize < 1 {
+		return nil
+	}
+	start := (page - 1) * pageSize
+	if start >= len(entries) {
+		return nil
+	}
+	end := start + pageSize
+	if end > len(entries) {
+		end = len(entries)
+	}
+	return entries[start:end]
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"testing"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyTx(id byte, from, to gosmtypes.Address, amount, fee uint64) *apitypes.Transaction {
+	return &apitypes.Transaction{
+		Id:         &apitypes.TransactionId{Id: []byte{id}},
+		Sender:     &apitypes.AccountId{Address: from.Bytes()},
+		Amount:     &apitypes.Amount{Value: amount},
+		GasOffered: &apitypes.GasOffered{GasPrice: fee},
+		Datum: &apitypes.Transaction_CoinTransfer{CoinTransfer: &apitypes.CoinTransferTransaction{
+			Receiver: &apitypes.AccountId{Address: to.Bytes()},
+		}},
+	}
+}
+
+func TestTxHistory(t *testing.T) {
+	me := gosmtypes.BytesToAddress([]byte{1})
+	other := gosmtypes.BytesToAddress([]byte{2})
+	third := gosmtypes.BytesToAddress([]byte{3})
+
+	txs := []*apitypes.Transaction{
+		historyTx(1, other, me, 100, 1),
+		historyTx(2, me, third, 30, 2),
+		historyTx(3, me, me, 0, 1),
+		historyTx(4, third, me, 50, 1),
+	}
+	entries := TxHistory(me, txs, 117, nil)
+	require.Len(t, entries, 4)
+	assert.Equal(t, []string{TxIn, TxOut, TxSelf, TxIn}, []string{entries[0].Direction, entries[1].Direction, entries[2].Direction, entries[3].Direction})
+	assert.Equal(t, third, entries[1].Counterparty)
+	assert.Equal(t, []uint64{100, 68, 67, 117}, []uint64{entries[0].Balance, entries[1].Balance, entries[2].Balance, entries[3].Balance})
+
+	// a reward after the second transaction
+	rewarded := TxHistory(me, txs, 127, []Credit{{After: 2, Amount: 10}})
+	assert.Equal(t, []uint64{100, 68, 77, 127}, []uint64{rewarded[0].Balance, rewarded[1].Balance, rewarded[2].Balance, rewarded[3].Balance})
+	assert.ElementsMatch(t, []Credit{{After: 0, Amount: 5}, {After: 2, Amount: 10}, {After: 4, Amount: 1}},
+		LayerCredits([]uint32{3, 5, 8, 8}, map[uint32]uint64{2: 5, 5: 10, 8: 1}))
+
+	assert.Len(t, TxFilter{Direction: TxIn}.Filter(entries), 3)
+	assert.Len(t, TxFilter{Direction: TxOut}.Filter(entries), 2)
+	assert.Len(t, TxFilter{Counterparty: &third}.Filter(entries), 2)
+	assert.Len(t, TxFilter{MinAmount: 30, MaxAmount: 50}.Filter(entries), 2)
+	assert.Len(t, TxFilter{Ids: map[string]bool{string([]byte{4}): true}}.Filter(entries), 1)
+
+	SortTxHistory(entries, SortByAmount, true)
+	assert.Equal(t, 1, entries[0].Index)
+	assert.Equal(t, 3, entries[3].Index)
+	SortTxHistory(entries, SortByLayer, true)
+	assert.Equal(t, 4, entries[0].Index)
+	SortTxHistory(entries, SortByLayer, false)
+	assert.Equal(t, 1, entries[0].Index)
+
+	assert.Len(t, PageTxHistory(entries, 1, 3), 3)
+	assert.Len(t, PageTxHistory(entries, 2, 3), 1)
+	assert.Empty(t, PageTxHistory(entries, 3, 3))
+	assert.Empty(t, PageTxHistory(entries, 0, 3))
+}
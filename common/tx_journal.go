@@ -0,0 +1,199 @@
+package common
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// PendingTransaction is a transaction submitted by this wallet which has not been processed yet
+type PendingTransaction struct {
+	Id        string    `json:"id"`
+	Recipient string    `json:"recipient"`
+	Nonce     uint64    `json:"nonce"`
+	Amount    uint64    `json:"amount"`
+	Fee       uint64    `json:"fee"`
+	GasLimit  uint64    `json:"gasLimit"`
+	Submitted time.Time `json:"submitted"`
+	Dropped   bool      `json:"dropped"`
+	Replaces  string    `json:"replaces,omitempty"` // id of the transaction this one replaces
+}
+
+// TxJournal is a per account journal of the transactions submitted by the wallet.
+// It hands out nonces so that transactions sent in quick succession don't collide.
+type TxJournal struct {
+	mu       sync.Mutex
+	path     string
+	Accounts map[string][]PendingTransaction `json:"accounts"`
+}
+
+// TxStateFunc returns the network state of a transaction
+type TxStateFunc func(txId []byte) (apitypes.TransactionState_TransactionState, error)
+
+// LoadTxJournal loads the journal stored at path. A missing file results in an empty journal.
+func LoadTxJournal(path string) (*TxJournal, error) {
+	j := &TxJournal{path: path, Accounts: make(map[string][]PendingTransaction)}
+	r, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening transactions journal: %v", err)
+	}
+	defer r.Close()
+
+	if err = json.NewDecoder(r).Decode(j); err != nil {
+		return nil, fmt.Errorf("invalid transactions journal content: %v", err)
+	}
+	if j.Accounts == nil {
+		j.Accounts = make(map[string][]PendingTransaction)
+	}
+	return j, nil
+}
+
+// save writes the journal back to its file
+func (j *TxJournal) save() error {
+	return WriteFileAtomic(j.path, 0600, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(j)
+	})
+}
+
+// Pending returns all the journal entries of an account
+func (j *TxJournal) Pending(address gosmtypes.Address) []PendingTransaction {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]PendingTransaction(nil), j.Accounts[address.String()]...)
+}
+
+// NextNonce returns the nonce to use for the next transaction of an account.
+// It is the projected nonce reported by the node, unless the journal holds pending
+// transactions the node doesn't know about yet.
+func (j *TxJournal) NextNonce(address gosmtypes.Address, projectedNonce uint64) uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	nonce := projectedNonce
+	for _, tx := range j.Accounts[address.String()] {
+		if !tx.Dropped && tx.Nonce >= nonce {
+			nonce = tx.Nonce + 1
+		}
+	}
+	return nonce
+}
+
+// Add records a newly submitted transaction and saves the journal
+func (j *TxJournal) Add(sender gosmtypes.Address, tx PendingTransaction) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key := sender.String()
+	j.Accounts[key] = append(j.Accounts[key], tx)
+	return j.save()
+}
+
+// Remove deletes a transaction from the journal of an account and saves the journal
+func (j *TxJournal) Remove(address gosmtypes.Address, txId string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key := address.String()
+	txs := j.Accounts[key][:0]
+	for _, tx := range j.Accounts[key] {
+		if tx.Id != txId {
+			txs = append(txs, tx)
+		}
+	}
+	j.setPending(key, txs)
+	return j.save()
+}
+
+// Get returns the journal entry of an account with the given transaction id
+func (j *TxJournal) Get(address gosmtypes.Address, txId string) (PendingTransaction, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, tx := range j.Accounts[address.String()] {
+		if tx.Id == txId {
+			return tx, true
+		}
+	}
+	return PendingTransaction{}, false
+}
+
+// Reconcile checks the journal entries of an account against the network.
+// Processed transactions are removed from the journal. Transactions which were rejected by the node,
+// or whose nonce has been used by another transaction, are flagged as dropped.
+// When one of several transactions sharing a nonce is processed the others are flagged as dropped.
+func (j *TxJournal) Reconcile(address gosmtypes.Address, currentNonce uint64, state TxStateFunc) (confirmed, dropped []PendingTransaction, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key := address.String()
+	remaining := make([]PendingTransaction, 0)
+	for _, tx := range j.Accounts[key] {
+		if tx.Dropped {
+			remaining = append(remaining, tx)
+			continue
+		}
+		id, err := hex.DecodeString(tx.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		s, err := state(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch s {
+		case apitypes.TransactionState_TRANSACTION_STATE_PROCESSED:
+			confirmed = append(confirmed, tx)
+			continue
+		case apitypes.TransactionState_TRANSACTION_STATE_REJECTED,
+			apitypes.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS,
+			apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING:
+			tx.Dropped = true
+		case apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED:
+			// unknown to the node and its nonce is already used
+			tx.Dropped = tx.Nonce < currentNonce
+		}
+		if tx.Dropped {
+			dropped = append(dropped, tx)
+		}
+		remaining = append(remaining, tx)
+	}
+	// a processed transaction replaces the other transactions with the same nonce
+	for i := range remaining {
+		for _, c := range confirmed {
+			if !remaining[i].Dropped && remaining[i].Nonce == c.Nonce {
+				remaining[i].Dropped = true
+				dropped = append(dropped, remaining[i])
+			}
+		}
+	}
+	j.setPending(key, remaining)
+	return confirmed, dropped, j.save()
+}
+
+// ClearDropped removes the transactions flagged as dropped from the journal of an account
+func (j *TxJournal) ClearDropped(address gosmtypes.Address) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key := address.String()
+	txs := j.Accounts[key][:0]
+	for _, tx := range j.Accounts[key] {
+		if !tx.Dropped {
+			txs = append(txs, tx)
+		}
+	}
+	j.setPending(key, txs)
+	return j.save()
+}
+
+func (j *TxJournal) setPending(key string, txs []PendingTransaction) {
+	if len(txs) == 0 {
+		delete(j.Accounts, key)
+		return
+	}
+	j.Accounts[key] = txs
+}
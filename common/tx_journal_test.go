@@ -0,0 +1,90 @@
+package common
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxJournalNextNonce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := LoadTxJournal(path)
+	require.NoError(t, err)
+
+	addr, _ := ParseAddress(checksummedAddress)
+	assert.Equal(t, uint64(3), j.NextNonce(addr, 3))
+
+	require.NoError(t, j.Add(addr, PendingTransaction{Id: "01", Nonce: 3}))
+	require.NoError(t, j.Add(addr, PendingTransaction{Id: "02", Nonce: 4}))
+	assert.Equal(t, uint64(5), j.NextNonce(addr, 3))
+	assert.Equal(t, uint64(7), j.NextNonce(addr, 7))
+
+	j2, err := LoadTxJournal(path)
+	require.NoError(t, err)
+	assert.Len(t, j2.Pending(addr), 2)
+	assert.Equal(t, uint64(5), j2.NextNonce(addr, 4))
+}
+
+func TestTxJournalReconcile(t *testing.T) {
+	j, err := LoadTxJournal(filepath.Join(t.TempDir(), "journal"))
+	require.NoError(t, err)
+	addr, _ := ParseAddress(checksummedAddress)
+
+	states := map[string]apitypes.TransactionState_TransactionState{
+		"01": apitypes.TransactionState_TRANSACTION_STATE_PROCESSED,
+		"02": apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL,
+		"03": apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING,
+		"04": apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED,
+	}
+	for i, id := range []string{"01", "02", "03", "04"} {
+		require.NoError(t, j.Add(addr, PendingTransaction{Id: id, Nonce: uint64(i)}))
+	}
+
+	confirmed, dropped, err := j.Reconcile(addr, 5, func(id []byte) (apitypes.TransactionState_TransactionState, error) {
+		return states[hex.EncodeToString(id)], nil
+	})
+	require.NoError(t, err)
+	require.Len(t, confirmed, 1)
+	assert.Equal(t, "01", confirmed[0].Id)
+	require.Len(t, dropped, 2)
+	assert.Len(t, j.Pending(addr), 3)
+
+	// dropped transactions don't hold on to their nonce
+	assert.Equal(t, uint64(2), j.NextNonce(addr, 0))
+
+	require.NoError(t, j.ClearDropped(addr))
+	assert.Len(t, j.Pending(addr), 1)
+	require.NoError(t, j.Remove(addr, "02"))
+	assert.Len(t, j.Pending(addr), 0)
+}
+
+func TestTxJournalReplacement(t *testing.T) {
+	j, err := LoadTxJournal(filepath.Join(t.TempDir(), "journal"))
+	require.NoError(t, err)
+	addr, _ := ParseAddress(checksummedAddress)
+
+	require.NoError(t, j.Add(addr, PendingTransaction{Id: "01", Nonce: 2, Fee: 1}))
+	require.NoError(t, j.Add(addr, PendingTransaction{Id: "02", Nonce: 2, Fee: 5, Replaces: "01"}))
+	tx, ok := j.Get(addr, "02")
+	require.True(t, ok)
+	assert.Equal(t, "01", tx.Replaces)
+	_, ok = j.Get(addr, "03")
+	assert.False(t, ok)
+
+	// the replacement is processed while the original is still in the mempool
+	confirmed, dropped, err := j.Reconcile(addr, 2, func(id []byte) (apitypes.TransactionState_TransactionState, error) {
+		if hex.EncodeToString(id) == "02" {
+			return apitypes.TransactionState_TRANSACTION_STATE_PROCESSED, nil
+		}
+		return apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, confirmed, 1)
+	assert.Equal(t, "01", confirmed[0].Replaces)
+	require.Len(t, dropped, 1)
+	assert.Equal(t, "01", dropped[0].Id)
+}
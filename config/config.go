@@ -0,0 +1,291 @@
+// Package config reads the wallet config file, which holds named network profiles.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/client"
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spf13/viper"
+)
+
+// DefaultProfile is the profile used when neither the config file nor the command line selects one
+const DefaultProfile = "local"
+
+// Profile holds the settings of one network
+type Profile struct {
+	Name      string   `mapstructure:"-"`
+	Servers   []string `mapstructure:"servers"` // API servers, in order of preference
+	Secure    bool     `mapstructure:"secure"`
+	WalletDir string   `mapstructure:"wallet_directory"`
+	Wallet    string   `mapstructure:"wallet"` // wallet file opened on start
+	Fee       string   `mapstructure:"fee"`    // default fee: a fee preset or a number of Smidge
+	Units     string   `mapstructure:"units"`  // display units of amounts
+	// time given to the API server to answer a call, or 0 for no deadline
+	Timeout time.Duration     `mapstructure:"timeout"`
+	TLS     client.TLSOptions `mapstructure:"tls"` // used with secure
+	// pin the public keys of the servers in the known servers file on first use, and refuse keys which change
+	Pin          bool   `mapstructure:"pin"`
+	KnownServers string `mapstructure:"known_servers"`
+	// URL of the SOCKS5 or HTTP proxy to the API servers, or direct. Empty uses the proxy environment variables.
+	Proxy string             `mapstructure:"proxy"`
+	Auth  client.AuthOptions `mapstructure:"auth"` // token sent to the API servers, used with secure
+}
+
+// Connection returns the settings of the connection to the API servers of the profile
+func (p *Profile) Connection() client.ConnectionOptions {
+	o := client.ConnectionOptions{Servers: p.Servers, Secure: p.Secure, TLS: p.TLS, Proxy: p.Proxy, Auth: p.Auth}
+	if p.Pin {
+		o.KnownServers = p.KnownServers
+	}
+	return o
+}
+
+// RegisterConnectionFlags defines the flags which override the TLS, key pinning, proxy and token settings of the profile
+func RegisterConnectionFlags(fs *flag.FlagSet) {
+	fs.String("tls-ca", "", "PEM bundle of the CAs which issue the server certificate, instead of the system CAs")
+	fs.String("tls-cert", "", "PEM client certificate, for servers which require mutual TLS")
+	fs.String("tls-key", "", "PEM key of the client certificate")
+	fs.String("tls-server-name", "", "Name expected in the server certificate and sent as SNI, instead of the server host")
+	fs.String("tls-min-version", "", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	fs.Bool("pin", false, "Pin the public keys of the servers on first use, and refuse to connect when they change")
+	fs.String("known-servers", DefaultKnownServersFile(), "File of the pinned public keys")
+	fs.String("token-file", "", "File holding the API token of the servers")
+	fs.String("token-env", "", "Environment variable holding the API token of the servers")
+	fs.String("proxy", "", "SOCKS5 or HTTP proxy to the servers, such as socks5://127.0.0.1:9050, or direct to ignore HTTPS_PROXY and ALL_PROXY")
+}
+
+// Server returns the preferred API server of the profile
+func (p *Profile) Server() string {
+	return p.Servers[0]
+}
+
+// WalletPath returns the path of the default wallet of the profile, or an empty string if it has none
+func (p *Profile) WalletPath() string {
+	if p.Wallet == "" || filepath.IsAbs(p.Wallet) {
+		return p.Wallet
+	}
+	return filepath.Join(p.WalletDir, p.Wallet)
+}
+
+// Override sets the settings of the profile given by the -server, -secure, -wallet_directory,
+// -wallet, -timeout, TLS, proxy and token flags, when they are set on the command line. -server may list several servers, separated by commas.
+func (p *Profile) Override(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		v := f.Value.String()
+		switch f.Name {
+		case "server":
+			p.Servers = splitServers(v)
+		case "secure":
+			p.Secure, err = strconv.ParseBool(v)
+		case "wallet_directory":
+			p.WalletDir = v
+		case "wallet":
+			p.Wallet = v
+		case "timeout":
+			p.Timeout, err = time.ParseDuration(v)
+		case "tls-ca":
+			p.TLS.CACert = v
+		case "tls-cert":
+			p.TLS.ClientCert = v
+		case "tls-key":
+			p.TLS.ClientKey = v
+		case "tls-server-name":
+			p.TLS.ServerName = v
+		case "tls-min-version":
+			p.TLS.MinVersion = v
+		case "pin":
+			p.Pin, err = strconv.ParseBool(v)
+		case "known-servers":
+			p.KnownServers = v
+		case "proxy":
+			p.Proxy = v
+		case "token-file":
+			p.Auth.Token, p.Auth.TokenFile, p.Auth.TokenEnv = "", v, ""
+		case "token-env":
+			p.Auth.Token, p.Auth.TokenFile, p.Auth.TokenEnv = "", "", v
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return p.validate()
+}
+
+func (p *Profile) validate() error {
+	if len(p.Servers) == 0 {
+		return errors.New("no API server")
+	}
+	if p.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %s", p.Timeout)
+	}
+	if p.TLS.IsSet() && !p.Secure {
+		return errors.New("the tls settings require a secure connection")
+	}
+	if p.Pin && !p.Secure {
+		return errors.New("pinning requires a secure connection")
+	}
+	if p.Auth.IsSet() && !p.Secure {
+		return errors.New("the auth settings require a secure connection: the token would be sent in clear")
+	}
+	if err := p.Auth.Validate(); err != nil {
+		return err
+	}
+	if p.TLS.MinVersion != "" {
+		if _, err := client.ParseTLSVersion(p.TLS.MinVersion); err != nil {
+			return err
+		}
+	}
+	if p.Proxy != "" && p.Proxy != client.ProxyDirect {
+		if _, err := client.ParseProxy(p.Proxy); err != nil {
+			return err
+		}
+	}
+	if _, err := strconv.ParseUint(p.Fee, 10, 64); err != nil {
+		valid := false
+		for _, preset := range common.FeePresets {
+			valid = valid || p.Fee == preset
+		}
+		if !valid {
+			return fmt.Errorf("invalid fee %s. Use a number of Smidge or one of %s", p.Fee, strings.Join(common.FeePresets, ", "))
+		}
+	}
+	return common.CheckAmountUnits(p.Units)
+}
+
+// Config is the content of the config file
+type Config struct {
+	File     string // empty when there is no config file
+	Default  string // name of the default profile
+	Profiles map[string]*Profile
+}
+
+// DefaultFile returns the path of the config file read when -config isn't set
+func DefaultFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cli_wallet", "config.yaml")
+}
+
+// DefaultKnownServersFile returns the path of the file of the pinned public keys of the servers
+func DefaultKnownServersFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "known_servers.json"
+	}
+	return filepath.Join(home, ".cli_wallet", "known_servers.json")
+}
+
+// localProfile is the profile of a node running on this computer, which is always available
+func localProfile() *Profile {
+	return &Profile{
+		Name:    DefaultProfile,
+		Servers: []string{"localhost:9092"},
+		Fee:     common.FeeNormal,
+		Units:   common.UnitsAuto,
+		Timeout: client.DefaultCallTimeout,
+
+		KnownServers: DefaultKnownServersFile(),
+	}
+}
+
+// Load reads a config file. A missing file is only an error when it was asked for:
+// without a file, the config only has the local profile.
+func Load(file string, required bool) (*Config, error) {
+	c := &Config{Default: DefaultProfile, Profiles: map[string]*Profile{DefaultProfile: localProfile()}}
+	if file == "" {
+		return c, nil
+	}
+	if _, err := os.Stat(file); os.IsNotExist(err) && !required {
+		return c, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", file, err)
+	}
+	c.File = file
+	if d := v.GetString("profile"); d != "" {
+		c.Default = d
+	}
+	for name := range v.GetStringMap("profiles") {
+		// profiles are based on the local profile, so that they only need to set what is different
+		p := localProfile()
+		if err := v.UnmarshalKey("profiles."+name, p); err != nil {
+			return nil, fmt.Errorf("invalid profile %s in %s: %v", name, file, err)
+		}
+		p.Name = name
+		p.WalletDir = expandHome(p.WalletDir)
+		p.TLS.CACert = expandHome(p.TLS.CACert)
+		p.TLS.ClientCert = expandHome(p.TLS.ClientCert)
+		p.TLS.ClientKey = expandHome(p.TLS.ClientKey)
+		p.KnownServers = expandHome(p.KnownServers)
+		p.Auth.TokenFile = expandHome(p.Auth.TokenFile)
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("invalid profile %s in %s: %v", name, file, err)
+		}
+		c.Profiles[name] = p
+	}
+	if _, ok := c.Profiles[c.Default]; !ok {
+		return nil, fmt.Errorf("unknown default profile %s in %s", c.Default, file)
+	}
+	return c, nil
+}
+
+// Profile returns a copy of the profile called name, or of the default profile when name is empty
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %s. Profiles: %s", name, strings.Join(c.Names(), ", "))
+	}
+	cp := *p
+	cp.Servers = append([]string{}, p.Servers...)
+	return &cp, nil
+}
+
+// Names returns the sorted names of the profiles
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitServers splits a comma separated list of servers
+func splitServers(list string) []string {
+	var servers []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
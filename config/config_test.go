@@ -0,0 +1,159 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+profile: testnet
+profiles:
+  testnet:
+    servers:
+      - api-1.testnet.example.org:443
+      - api-2.testnet.example.org:443
+    secure: true
+    wallet_directory: /var/wallets/testnet
+    wallet: ops.json
+    fee: fast
+    units: smh
+    timeout: 10s
+    pin: true
+    known_servers: /etc/wallet/known_servers.json
+    tls:
+      ca: /etc/wallet/ca.pem
+      server_name: api.internal
+      min_version: 1.2
+    auth:
+      token_file: ~/.cli_wallet/testnet.token
+      header: x-api-key
+  devnet:
+    fee: "5"
+    proxy: socks5h://127.0.0.1:9050
+`
+
+func writeConfig(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+	return file
+}
+
+func TestLoad(t *testing.T) {
+	c, err := Load(writeConfig(t, testConfig), true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"devnet", "local", "testnet"}, c.Names())
+
+	p, err := c.Profile("")
+	require.NoError(t, err)
+	assert.Equal(t, "testnet", p.Name)
+	assert.Equal(t, "api-1.testnet.example.org:443", p.Server())
+	assert.Len(t, p.Servers, 2)
+	assert.True(t, p.Secure)
+	assert.Equal(t, "/var/wallets/testnet/ops.json", p.WalletPath())
+	assert.Equal(t, "fast", p.Fee)
+	assert.Equal(t, "smh", p.Units)
+	assert.Equal(t, 10*time.Second, p.Timeout)
+	assert.Equal(t, "/etc/wallet/ca.pem", p.Connection().TLS.CACert)
+	assert.Equal(t, "api.internal", p.TLS.ServerName)
+	assert.Equal(t, "1.2", p.TLS.MinVersion)
+	assert.Equal(t, "/etc/wallet/known_servers.json", p.Connection().KnownServers)
+	assert.Equal(t, expandHome("~/.cli_wallet/testnet.token"), p.Connection().Auth.TokenFile)
+	assert.Equal(t, "x-api-key", p.Auth.Header)
+
+	// settings which are not set come from the local profile
+	p, err = c.Profile("devnet")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:9092", p.Server())
+	assert.False(t, p.Secure)
+	assert.Equal(t, "5", p.Fee)
+	assert.Equal(t, "auto", p.Units)
+	assert.Equal(t, 30*time.Second, p.Timeout)
+	assert.Equal(t, "", p.WalletPath())
+	assert.Empty(t, p.Connection().KnownServers, "pinning is off by default")
+	assert.Equal(t, "socks5h://127.0.0.1:9050", p.Connection().Proxy)
+
+	_, err = c.Profile("mainnet")
+	assert.Error(t, err)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	c, err := Load(file, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local"}, c.Names())
+	assert.Empty(t, c.File)
+
+	_, err = Load(file, true)
+	assert.Error(t, err)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, content := range []string{
+		"profile: mainnet\n",
+		"profiles:\n  x:\n    fee: cheap\n",
+		"profiles:\n  x:\n    units: btc\n",
+		"profiles:\n  x:\n    timeout: soon\n",
+		"profiles:\n  x:\n    tls:\n      ca: ca.pem\n",
+		"profiles:\n  x:\n    pin: true\n",
+		"profiles:\n  x:\n    secure: true\n    tls:\n      min_version: 1.4\n",
+		"profiles:\n  x:\n    proxy: ftp://proxy:21\n",
+		"profiles:\n  x:\n    auth:\n      token: secret\n",
+		"profiles:\n  x:\n    secure: true\n    auth:\n      token: secret\n      token_env: API_TOKEN\n",
+		"profiles:\n  x:\n    proxy: 127.0.0.1:9050\n",
+	} {
+		_, err := Load(writeConfig(t, content), true)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestProfileCopy(t *testing.T) {
+	c, err := Load("", false)
+	require.NoError(t, err)
+	p, err := c.Profile(DefaultProfile)
+	require.NoError(t, err)
+	p.Servers[0] = "changed:1"
+	p, err = c.Profile(DefaultProfile)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:9092", p.Server())
+}
+
+func TestOverride(t *testing.T) {
+	c, err := Load(writeConfig(t, testConfig), true)
+	require.NoError(t, err)
+	p, err := c.Profile("testnet")
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("server", "localhost:9092", "")
+	fs.Bool("secure", false, "")
+	fs.String("wallet_directory", ".", "")
+	fs.String("wallet", "", "")
+	require.NoError(t, fs.Parse([]string{"-server", "10.0.0.1:9092", "-wallet", "other.json"}))
+	require.NoError(t, p.Override(fs))
+	assert.Equal(t, []string{"10.0.0.1:9092"}, p.Servers)
+	assert.True(t, p.Secure, "flags which are not set don't override the profile")
+	assert.Equal(t, "/var/wallets/testnet/other.json", p.WalletPath())
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterConnectionFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-tls-cert", "wallet.crt", "-tls-key", "wallet.key", "-proxy", "direct", "-token-env", "API_TOKEN"}))
+	require.NoError(t, p.Override(fs))
+	assert.Equal(t, "direct", p.Proxy)
+	assert.Equal(t, client.AuthOptions{TokenEnv: "API_TOKEN", Header: "x-api-key"}, p.Auth, "the token flags replace the token of the profile")
+	assert.Equal(t, "wallet.crt", p.TLS.ClientCert)
+	assert.Equal(t, "wallet.key", p.TLS.ClientKey)
+	assert.Equal(t, "api.internal", p.TLS.ServerName)
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("server", "localhost:9092", "")
+	require.NoError(t, fs.Parse([]string{"-server", "10.0.0.1:9092, 10.0.0.2:9092"}))
+	require.NoError(t, p.Override(fs))
+	assert.Equal(t, []string{"10.0.0.1:9092", "10.0.0.2:9092"}, p.Servers)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 	"gopkg.in/op/go-logging.v1"
@@ -20,6 +21,7 @@ type Log struct {
 // smlogger is the local app singleton logger.
 var AppLog Log
 var debugMode = false
+var errorCount uint64
 
 func init() {
 
@@ -126,9 +128,21 @@ func Debug(format string, args ...interface{}) {
 
 // Error prints formatted error level log message.
 func Error(format string, args ...interface{}) {
+	atomic.AddUint64(&errorCount, 1)
 	AppLog.Error(format, args...)
 }
 
+// BackgroundError prints formatted error level log message of a background task, such as the scheduler.
+// It isn't counted by ErrorCount: the command running at the time didn't fail.
+func BackgroundError(format string, args ...interface{}) {
+	AppLog.Error(format, args...)
+}
+
+// ErrorCount returns the number of messages logged with Error, so that callers can tell whether an operation failed.
+func ErrorCount() uint64 {
+	return atomic.LoadUint64(&errorCount)
+}
+
 // Warning prints formatted warning level log message.
 func Warning(format string, args ...interface{}) {
 	AppLog.Warning(format, args...)
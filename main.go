@@ -3,11 +3,18 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/spacemeshos/CLIWallet/cli"
 	"github.com/spacemeshos/CLIWallet/client"
 	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/config"
+	"github.com/spacemeshos/CLIWallet/output"
 	"github.com/spacemeshos/CLIWallet/repl"
 )
 
@@ -27,42 +34,163 @@ func (m mockClient) Transfer(from, to, amount, passphrase string) error {
 
 func main() {
 
+	// non-interactive subcommands don't need a terminal
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	var (
-		dataDir    string
-		walletName string
-		be         *client.WalletBackend
+		dataDir      string
+		walletName   string
+		daemon       bool
+		jsonOutput   bool
+		script       string
+		configFile   string
+		passwordFile string
+		profileName  string
+		timeout      time.Duration
+		be           *client.WalletBackend
 	)
 	grpcServer := client.DefaultGRPCServer
 	secureConnection := client.DefaultSecureConnection
 
-	flag.StringVar(&grpcServer, "server", grpcServer, fmt.Sprintf("The Spacemesh api grpc server host and port. Defaults to %s", client.DefaultGRPCServer))
+	flag.StringVar(&grpcServer, "server", grpcServer, fmt.Sprintf("The Spacemesh api grpc server host and port, or a comma separated list of servers to fail over to. Defaults to %s", client.DefaultGRPCServer))
 	flag.BoolVar(&secureConnection, "secure", secureConnection, "Connect securely to the server. Default is false")
+	flag.DurationVar(&timeout, "timeout", client.DefaultCallTimeout, "time given to the api server to answer a call. 0 means no deadline")
 	flag.StringVar(&dataDir, "wallet_directory", getwd(), "set default wallet directory")
 	flag.StringVar(&walletName, "wallet", "", "set the name of wallet to open")
+	flag.BoolVar(&daemon, "daemon", false, "run the scheduled payments of the wallet set with -wallet without starting the REPL")
+	flag.StringVar(&passwordFile, "password-file", "", fmt.Sprintf("file holding the wallet password in daemon mode. Defaults to the %s environment variable", cli.DefaultPasswordEnv))
+	flag.BoolVar(&jsonOutput, "json", false, "display the results of commands as json")
+	flag.StringVar(&script, "exec", "", "run the commands of a script file and exit")
+	config.RegisterConnectionFlags(flag.CommandLine)
+	flag.StringVar(&configFile, "config", "", fmt.Sprintf("config file with the network profiles. Defaults to %s", config.DefaultFile()))
+	flag.StringVar(&profileName, "profile", "", "network profile of the config file to use. The flags above override its settings")
 
 	flag.Parse()
 
-	_, err := syscall.Open("/dev/tty", syscall.O_RDONLY, 0)
+	cfg, err := config.Load(configOrDefault(configFile), configFile != "")
 	if err != nil {
-		println(err)
-		os.Exit(1)
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	profile, err := cfg.Profile(profileName)
+	if err == nil {
+		err = profile.Override(flag.CommandLine)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if profile.WalletDir == "" {
+		profile.WalletDir = getwd()
+	}
+	if err := common.SetAmountUnits(profile.Units); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
 	}
 
-	be, err = client.OpenConnection(grpcServer, secureConnection, dataDir)
+	if daemon {
+		// the daemon runs from systemd or cron: it has no terminal to prompt for the password
+		if profile.Wallet == "" {
+			fmt.Println("-daemon requires a wallet set with -wallet or in the profile")
+			os.Exit(2)
+		}
+		os.Exit(runDaemon(profile, passwordFile))
+	}
+
+	// scripts run from cron or CI without a terminal
+	if script == "" {
+		if _, err = syscall.Open("/dev/tty", syscall.O_RDONLY, 0); err != nil {
+			println(err)
+			os.Exit(1)
+		}
+	}
+
+	be, err = client.OpenConnection(profile.Connection(), profile.WalletDir)
 	if err != nil {
 		os.Exit(1)
 	}
-	if walletName != "" {
-		walletPath := dataDir + "/" + walletName
+	if walletPath := profile.WalletPath(); walletPath != "" {
 		fmt.Println("opening ", walletPath)
-		be, err = client.OpenWalletBackend(walletPath, grpcServer, secureConnection)
+		be, err = client.OpenWalletBackend(walletPath, profile.Connection())
 		if err != nil {
 			fmt.Println("failed to open wallet : ", err)
 			os.Exit(1)
 		}
+		be.SetWorkingDirectory(profile.WalletDir)
 	}
+	be.SetTimeout(profile.Timeout)
+
+	settings := repl.Settings{Format: output.FormatText, Config: cfg, Profile: profile}
+	if jsonOutput {
+		settings.Format = output.FormatJSON
+	}
+	if script != "" {
+		if err := repl.Exec(be, settings, script); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	repl.Start(be, settings)
+}
+
+// configOrDefault returns the config file set with -config, or the default config file
+func configOrDefault(file string) string {
+	if file != "" {
+		return file
+	}
+	return config.DefaultFile()
+}
+
+// daemonPassword reads the wallet password from the password file, or else from the password environment variable
+func daemonPassword(passwordFile string) (string, error) {
+	if passwordFile == "" {
+		password, ok := os.LookupEnv(cli.DefaultPasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("no wallet password. Set %s or use -password-file", cli.DefaultPasswordEnv)
+		}
+		return password, nil
+	}
+	b, err := ioutil.ReadFile(passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the wallet password: %v", err)
+	}
+	// password files usually end with a new line
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// runDaemon runs the scheduled payments of the wallet of the profile until interrupted, and returns the exit code
+func runDaemon(profile *config.Profile, passwordFile string) int {
+	password, err := daemonPassword(passwordFile)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	be, err := client.OpenWalletBackendWithPassword(profile.WalletPath(), password, profile.Connection())
+	if err != nil {
+		fmt.Println("failed to open wallet : ", err)
+		return 1
+	}
+	be.SetWorkingDirectory(profile.WalletDir)
+	be.SetTimeout(profile.Timeout)
+
+	fmt.Println("running scheduled payments. Press Ctrl-C to stop")
+	stop := be.StartScheduler(client.DefaultSchedulerInterval, func(e common.ScheduleExecution) {
+		result := "transaction id: " + e.TxId
+		if e.Error != "" {
+			result = "failed: " + e.Error
+		}
+		fmt.Println(e.Time.Format("2006-01-02 15:04:05"), "layer", e.Layer, "schedule", e.ScheduleId, common.FormatAmount(e.Amount), "to", e.Recipient, result)
+	})
+	defer stop()
 
-	repl.Start(be)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	fmt.Println("stopping scheduler")
+	return 0
 }
 
 func getwd() string {
@@ -0,0 +1,97 @@
+// Package output renders the results of wallet commands as text for people or as JSON for programs.
+// Both renderers work on the same views, so the two outputs of a command always hold the same data.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// View is the result of a command. Its exported fields are its JSON schema.
+type View interface {
+	// Lines returns the view as human readable lines of text
+	Lines() []string
+}
+
+// Renderer writes views
+type Renderer interface {
+	Render(v View) error
+	// RenderError reports a failed command
+	RenderError(err error) error
+}
+
+// New returns the renderer for format, writing to w. Text lines start with prefix.
+func New(format string, w io.Writer, prefix string) (Renderer, error) {
+	switch format {
+	case FormatText:
+		return &Text{W: w, Prefix: prefix}, nil
+	case FormatJSON:
+		return &JSON{W: w}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %s. Use %s or %s", format, FormatText, FormatJSON)
+}
+
+// Text writes views as lines of text
+type Text struct {
+	W      io.Writer
+	Prefix string
+}
+
+func (t *Text) println(s string) error {
+	var err error
+	if t.Prefix == "" {
+		_, err = fmt.Fprintln(t.W, s)
+	} else {
+		_, err = fmt.Fprintln(t.W, t.Prefix, s)
+	}
+	return err
+}
+
+// Render writes the lines of v
+func (t *Text) Render(v View) error {
+	for _, l := range v.Lines() {
+		if err := t.println(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderError writes err as a line of text
+func (t *Text) RenderError(err error) error {
+	return t.println("Error: " + err.Error())
+}
+
+// JSON writes each view as one indented JSON document
+type JSON struct {
+	W io.Writer
+}
+
+// Render writes v as JSON
+func (j *JSON) Render(v View) error {
+	enc := json.NewEncoder(j.W)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// RenderError writes err as an Error view
+func (j *JSON) RenderError(err error) error {
+	return j.Render(Error{Error: err.Error()})
+}
+
+// Error is the view of a failed command
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Lines returns the error message
+func (e Error) Lines() []string {
+	return []string{"Error: " + e.Error}
+}
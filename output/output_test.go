@@ -0,0 +1,89 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var b bytes.Buffer
+	r, err := New(FormatText, &b, ">")
+	require.NoError(t, err)
+	assert.IsType(t, &Text{}, r)
+	r, err = New(FormatJSON, &b, ">")
+	require.NoError(t, err)
+	assert.IsType(t, &JSON{}, r)
+	_, err = New("xml", &b, ">")
+	assert.Error(t, err)
+}
+
+func TestText(t *testing.T) {
+	var b bytes.Buffer
+	r := &Text{W: &b, Prefix: ">"}
+	require.NoError(t, r.Render(GlobalState{RootHash: "0x01", Layer: 7}))
+	require.NoError(t, r.RenderError(errors.New("failed")))
+	assert.Equal(t, "> Hash: 0x01\n> Layer: 7\n> Error: failed\n", b.String())
+
+	b.Reset()
+	r.Prefix = ""
+	require.NoError(t, r.Render(Accounts{Accounts: []AccountSummary{{Name: "main", Address: "0xAA"}}}))
+	assert.Equal(t, "0xAA\tmain\n", b.String())
+}
+
+func TestJSON(t *testing.T) {
+	var b bytes.Buffer
+	r := &JSON{W: &b}
+	require.NoError(t, r.Render(Account{Name: "main", Address: "0xAA", Balance: 5, PublicKey: "0x01", PrivateKey: "0x02"}))
+	assert.JSONEq(t, `{"name":"main","address":"0xAA","balance":5,"nonce":0,"projectedBalance":0,"projectedNonce":0,"publicKey":"0x01"}`, b.String())
+	assert.NotContains(t, b.String(), "0x02")
+
+	b.Reset()
+	require.NoError(t, r.RenderError(errors.New("failed")))
+	assert.JSONEq(t, `{"error":"failed"}`, b.String())
+}
+
+// the schemas are documented in docs/json-output.md and must not change silently
+func TestSchemas(t *testing.T) {
+	keys := func(v View) []string {
+		b, err := json.Marshal(v)
+		require.NoError(t, err)
+		m := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(b, &m))
+		k := make([]string, 0, len(m))
+		for key := range m {
+			k = append(k, key)
+		}
+		return k
+	}
+	assert.ElementsMatch(t, []string{"total", "matching", "page", "pages", "transactions"}, keys(Transactions{}))
+	assert.ElementsMatch(t, []string{"total", "rewards"}, keys(Rewards{}))
+	assert.ElementsMatch(t, []string{"synced", "syncedLayer", "topLayer", "verifiedLayer", "peers"}, keys(Node{}))
+	assert.ElementsMatch(t, []string{"netId", "genesisTime", "layerDuration", "layersPerEpoch", "currentLayer", "currentEpoch", "maxTxsPerSecond"}, keys(Net{}))
+	assert.ElementsMatch(t, []string{"rootHash", "layer"}, keys(GlobalState{}))
+	assert.ElementsMatch(t, []string{"smeshing"}, keys(Smeshing{}))
+	assert.ElementsMatch(t, []string{"nonce", "fee", "dryRun", "warnings"}, keys(Transfer{}))
+	assert.ElementsMatch(t, []string{"servers"}, keys(KnownServers{}))
+}
+
+func TestTransactionsLines(t *testing.T) {
+	v := Transactions{Total: 3, Matching: 1, Page: 1, Pages: 1, Transactions: []Transaction{
+		{Index: 2, Id: "0x0123456789abcdef", Direction: "in", Counterparty: "0xAA", Amount: 10, Fee: 1, Balance: 10},
+	}}
+	lines := v.Lines()
+	require.Len(t, lines, 5)
+	assert.Equal(t, "Matching transactions: 1, page 1 of 1", lines[1])
+	assert.Contains(t, lines[3], "0x0123456789ab")
+	assert.NotContains(t, lines[3], "0x0123456789abc")
+}
+
+func TestNetGenesisTime(t *testing.T) {
+	b, err := json.Marshal(Net{GenesisTime: time.Unix(1600000000, 0).UTC()})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"genesisTime":"2020-09-13T12:26:40Z"`)
+}
@@ -0,0 +1,378 @@
+package output
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// Amounts are in Smidge, addresses are checksummed hex strings and ids and hashes are 0x prefixed hex strings.
+
+// Account is the state of an account
+type Account struct {
+	Name             string `json:"name,omitempty"`
+	Address          string `json:"address"`
+	Balance          uint64 `json:"balance"`
+	Nonce            uint64 `json:"nonce"`
+	ProjectedBalance uint64 `json:"projectedBalance"`
+	ProjectedNonce   uint64 `json:"projectedNonce"`
+	PublicKey        string `json:"publicKey,omitempty"`
+	PrivateKey       string `json:"-"` // displayed but never encoded, so that JSON output can be logged safely
+}
+
+// Lines returns the account info
+func (a Account) Lines() []string {
+	lines := make([]string, 0)
+	if a.Name != "" {
+		lines = append(lines, "Local alias: "+a.Name)
+	}
+	lines = append(lines,
+		"Address: "+a.Address,
+		"Balance: "+common.FormatAmount(a.Balance),
+		fmt.Sprint("Nonce: ", a.Nonce),
+		"Projected Balance: "+common.FormatAmount(a.ProjectedBalance),
+		fmt.Sprint("Projected Nonce: ", a.ProjectedNonce),
+		"Projected account state includes all pending transactions that haven't been added to the mesh yet.")
+	if a.PublicKey != "" {
+		lines = append(lines, "Public key: "+a.PublicKey)
+	}
+	if a.PrivateKey != "" {
+		lines = append(lines, "Private key: "+a.PrivateKey)
+	}
+	return lines
+}
+
+// AccountSummary names an account of a wallet
+type AccountSummary struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// Accounts are the accounts of a wallet
+type Accounts struct {
+	Accounts []AccountSummary `json:"accounts"`
+}
+
+// Lines returns one line per account
+func (a Accounts) Lines() []string {
+	lines := make([]string, len(a.Accounts))
+	for i, acc := range a.Accounts {
+		lines[i] = acc.Address + "\t" + acc.Name
+	}
+	return lines
+}
+
+// Transaction is a mesh transaction seen from one account
+type Transaction struct {
+	Index        int    `json:"index"` // position in layer order, starting from 1 for the oldest transaction
+	Id           string `json:"id"`
+	Direction    string `json:"direction"` // in, out or self
+	Counterparty string `json:"counterparty"`
+	Amount       uint64 `json:"amount"`
+	Fee          uint64 `json:"fee"`
+	Balance      uint64 `json:"balance"` // account balance after the transaction, not counting rewards
+}
+
+// NewTransaction returns the view of a history entry
+func NewTransaction(e common.TxHistoryEntry) Transaction {
+	return Transaction{
+		Index:        e.Index,
+		Id:           fmt.Sprintf("0x%x", e.Tx.GetId().GetId()),
+		Direction:    e.Direction,
+		Counterparty: e.Counterparty.String(),
+		Amount:       e.Amount,
+		Fee:          e.Fee,
+		Balance:      e.Balance,
+	}
+}
+
+// Transactions is a page of the transaction history of an account
+type Transactions struct {
+	Total        uint32        `json:"total"`    // mesh transactions of the account
+	Matching     int           `json:"matching"` // transactions selected by the filters
+	Page         int           `json:"page"`
+	Pages        int           `json:"pages"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Lines returns the page as a table
+func (t Transactions) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("Total mesh transactions: %d", t.Total),
+		fmt.Sprintf("Matching transactions: %d, page %d of %d", t.Matching, t.Page, t.Pages),
+	}
+	if len(t.Transactions) == 0 {
+		return lines
+	}
+	lines = append(lines, fmt.Sprintf("%5s  %-4s  %-42s  %22s  %8s  %22s  %s", "#", "Dir", "Counterparty", "Amount", "Fee", "Balance", "Id"))
+	for _, tx := range t.Transactions {
+		id := tx.Id
+		if len(id) > 14 {
+			id = id[:14]
+		}
+		lines = append(lines, fmt.Sprintf("%5d  %-4s  %-42s  %22s  %8d  %22s  %s",
+			tx.Index, tx.Direction, tx.Counterparty, common.FormatAmount(tx.Amount), tx.Fee, common.FormatAmount(tx.Balance), id))
+	}
+	return append(lines, "Balances don't include rewards")
+}
+
+// Reward is a smeshing reward
+type Reward struct {
+	Layer       uint32 `json:"layer"`
+	LayerReward uint64 `json:"layerReward"`
+	Fees        uint64 `json:"fees"`
+	Total       uint64 `json:"total"`
+	Coinbase    string `json:"coinbase"` // rewards account
+}
+
+// NewRewards returns the view of rewards, of which there are total
+func NewRewards(rewards []*apitypes.Reward, total uint32) Rewards {
+	v := Rewards{Total: total, Rewards: make([]Reward, len(rewards))}
+	for i, r := range rewards {
+		v.Rewards[i] = Reward{
+			Layer:       r.Layer.GetNumber(),
+			LayerReward: r.LayerReward.GetValue(),
+			Fees:        r.Total.GetValue() - r.LayerReward.GetValue(),
+			Total:       r.Total.GetValue(),
+			Coinbase:    gosmtypes.BytesToAddress(r.Coinbase.GetAddress()).String(),
+		}
+	}
+	return v
+}
+
+// Rewards are the rewards of an account or of a smesher
+type Rewards struct {
+	Total   uint32   `json:"total"`
+	Rewards []Reward `json:"rewards"`
+}
+
+// Lines returns the rewards, separated by dashes
+func (r Rewards) Lines() []string {
+	lines := []string{fmt.Sprintf("Total rewards: %d", r.Total)}
+	for _, rw := range r.Rewards {
+		lines = append(lines,
+			fmt.Sprint("Rewarded on layer: ", rw.Layer),
+			fmt.Sprintf("Layer reward %d Smidge", rw.LayerReward),
+			fmt.Sprintf("Transaction fees %d Smidge", rw.Fees),
+			fmt.Sprintf("Total reward %d Smidge", rw.Total),
+			"Rewards account: "+rw.Coinbase,
+			"-----")
+	}
+	return lines
+}
+
+// Node is the version and sync status of the node
+type Node struct {
+	Version       string `json:"version,omitempty"`
+	Build         string `json:"build,omitempty"`
+	Server        string `json:"server,omitempty"` // API server the wallet is connected to
+	Synced        bool   `json:"synced"`
+	SyncedLayer   uint32 `json:"syncedLayer"`
+	TopLayer      uint32 `json:"topLayer"`
+	VerifiedLayer uint32 `json:"verifiedLayer"`
+	Peers         uint64 `json:"peers"`
+}
+
+// Lines returns the node status
+func (n Node) Lines() []string {
+	lines := make([]string, 0)
+	if n.Version != "" {
+		lines = append(lines, "Version: "+n.Version, "Build: "+n.Build)
+	}
+	if n.Server != "" {
+		lines = append(lines, "API server: "+n.Server)
+	}
+	return append(lines,
+		fmt.Sprint("Synced: ", n.Synced),
+		fmt.Sprint("Synced layer: ", n.SyncedLayer),
+		fmt.Sprint("Current layer: ", n.TopLayer),
+		fmt.Sprint("Verified layer: ", n.VerifiedLayer),
+		fmt.Sprint("Peers: ", n.Peers))
+}
+
+// Net holds the network parameters
+type Net struct {
+	NetId           uint64    `json:"netId"`
+	GenesisTime     time.Time `json:"genesisTime"`
+	LayerDuration   uint64    `json:"layerDuration"` // seconds
+	LayersPerEpoch  uint64    `json:"layersPerEpoch"`
+	CurrentLayer    uint32    `json:"currentLayer"`
+	CurrentEpoch    uint64    `json:"currentEpoch"`
+	MaxTxsPerSecond uint64    `json:"maxTxsPerSecond"`
+}
+
+// NewNet returns the view of the network info
+func NewNet(info *common.NetInfo) Net {
+	return Net{
+		NetId:           info.NetId,
+		GenesisTime:     time.Unix(int64(info.GenesisTime), 0).UTC(),
+		LayerDuration:   info.LayerDuration,
+		LayersPerEpoch:  info.LayerPerEpoch,
+		CurrentLayer:    info.CurrentLayer,
+		CurrentEpoch:    info.CurrentEpoch,
+		MaxTxsPerSecond: info.MaxTxsPerSec,
+	}
+}
+
+// Lines returns the network parameters
+func (n Net) Lines() []string {
+	return []string{
+		fmt.Sprint("Network Id: ", n.NetId),
+		fmt.Sprint("Max transactions per second: ", n.MaxTxsPerSecond),
+		fmt.Sprint("Layers per epoch: ", n.LayersPerEpoch),
+		fmt.Sprintf("Layer duration: %d seconds", n.LayerDuration),
+		fmt.Sprint("Current layer number: ", n.CurrentLayer),
+		fmt.Sprint("Current epoch number: ", n.CurrentEpoch),
+		"Genesis time: " + n.GenesisTime.Local().String(),
+	}
+}
+
+// GlobalState is the most recent global state hash
+type GlobalState struct {
+	RootHash string `json:"rootHash"`
+	Layer    uint32 `json:"layer"`
+}
+
+// Lines returns the hash and its layer
+func (g GlobalState) Lines() []string {
+	return []string{"Hash: " + g.RootHash, fmt.Sprint("Layer: ", g.Layer)}
+}
+
+// Smeshing tells whether the node is smeshing
+type Smeshing struct {
+	Smeshing bool `json:"smeshing"`
+}
+
+// Lines returns the smeshing state
+func (s Smeshing) Lines() []string {
+	if s.Smeshing {
+		return []string{"Smeshing is on"}
+	}
+	return []string{"Smeshing is off"}
+}
+
+// SmesherId is the id of the node smesher
+type SmesherId struct {
+	SmesherId string `json:"smesherId"`
+}
+
+// Lines returns the smesher id
+func (s SmesherId) Lines() []string {
+	return []string{"Smesher id: " + s.SmesherId}
+}
+
+// RewardsAccount is the account the node smesher is rewarded to
+type RewardsAccount struct {
+	Address string `json:"address"`
+}
+
+// Lines returns the rewards address
+func (r RewardsAccount) Lines() []string {
+	return []string{"Rewards address is: " + r.Address}
+}
+
+// Receipt is the outcome of a processed transaction
+type Receipt struct {
+	Result  string `json:"result"`
+	Layer   uint32 `json:"layer"`
+	GasUsed uint64 `json:"gasUsed"`
+	FeePaid uint64 `json:"feePaid"`
+}
+
+// TxStatus is the state of a transaction
+type TxStatus struct {
+	Id      string   `json:"id"`
+	State   string   `json:"state"`
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to,omitempty"`
+	Amount  uint64   `json:"amount"`
+	Fee     uint64   `json:"fee"`
+	Nonce   uint64   `json:"nonce"`
+	Receipt *Receipt `json:"receipt,omitempty"`
+}
+
+// Lines returns the transaction state as key: value lines
+func (t TxStatus) Lines() []string {
+	lines := []string{"id: " + t.Id, "state: " + t.State}
+	if t.From == "" {
+		return lines
+	}
+	lines = append(lines, "from: "+t.From)
+	if t.To != "" {
+		lines = append(lines, "to: "+t.To)
+	}
+	lines = append(lines,
+		fmt.Sprint("amount: ", t.Amount),
+		fmt.Sprint("fee: ", t.Fee),
+		fmt.Sprint("nonce: ", t.Nonce))
+	if t.Receipt != nil {
+		lines = append(lines,
+			"result: "+t.Receipt.Result,
+			fmt.Sprint("layer: ", t.Receipt.Layer),
+			fmt.Sprint("gas used: ", t.Receipt.GasUsed),
+			fmt.Sprint("fee paid: ", t.Receipt.FeePaid))
+	}
+	return lines
+}
+
+// Transfer is the outcome of sending coins. A transfer which was not sent has no id and state.
+type Transfer struct {
+	Id       string   `json:"id,omitempty"`
+	State    string   `json:"state,omitempty"`
+	Nonce    uint64   `json:"nonce"`
+	Fee      uint64   `json:"fee"`
+	DryRun   bool     `json:"dryRun"`
+	Warnings []string `json:"warnings"`
+}
+
+// Lines returns the warnings followed by the transaction id and state, or its nonce and fee for a dry run
+func (t Transfer) Lines() []string {
+	lines := make([]string, 0)
+	for _, w := range t.Warnings {
+		lines = append(lines, "warning: "+w)
+	}
+	if t.DryRun {
+		return append(lines, fmt.Sprint("nonce: ", t.Nonce), fmt.Sprint("fee: ", t.Fee))
+	}
+	if t.Id != "" {
+		lines = append(lines, "id: "+t.Id, "state: "+t.State)
+	}
+	return lines
+}
+
+// KnownServer is an API server with a pinned public key
+type KnownServer struct {
+	Server      string    `json:"server"`
+	Fingerprint string    `json:"fingerprint"`
+	Subject     string    `json:"subject,omitempty"`
+	Added       time.Time `json:"added"`
+}
+
+// KnownServers are the API servers with a pinned public key
+type KnownServers struct {
+	Servers []KnownServer `json:"servers"`
+}
+
+// NewKnownServers returns the view of the known servers
+func NewKnownServers(servers []common.KnownServer) KnownServers {
+	v := KnownServers{Servers: make([]KnownServer, len(servers))}
+	for i, s := range servers {
+		v.Servers[i] = KnownServer{Server: s.Server, Fingerprint: s.Fingerprint, Subject: s.Subject, Added: s.Added}
+	}
+	return v
+}
+
+// Lines returns one line per server
+func (k KnownServers) Lines() []string {
+	if len(k.Servers) == 0 {
+		return []string{"No known servers"}
+	}
+	lines := make([]string, len(k.Servers))
+	for i, s := range k.Servers {
+		lines[i] = fmt.Sprintf("%s\t%s\t%s, pinned %s", s.Server, s.Fingerprint, s.Subject, s.Added.Format("2006-01-02 15:04"))
+	}
+	return lines
+}
@@ -3,10 +3,11 @@ package repl
 import (
 	"encoding/hex"
 	"fmt"
+	"strconv"
 
 	"github.com/spacemeshos/CLIWallet/common"
 	"github.com/spacemeshos/CLIWallet/log"
-	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/CLIWallet/output"
 	"github.com/spacemeshos/ed25519"
 	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
 )
@@ -24,6 +25,7 @@ func (r *repl) openWallet() {
 	}
 	r.client.WalletInfo()
 	r.initializeCommands()
+	r.startScheduler()
 }
 
 func (r *repl) createWallet() {
@@ -34,9 +36,11 @@ func (r *repl) createWallet() {
 	}
 	r.client.WalletInfo()
 	r.initializeCommands()
+	r.startScheduler()
 }
 
 func (r *repl) closeWallet() {
+	r.stopScheduler()
 	r.client.CloseWallet()
 	r.clientOpen = false
 	r.initializeCommands()
@@ -49,7 +53,7 @@ func (r *repl) chooseAccount() {
 		return
 	}
 	if len(accs) == 0 {
-		r.createAccount()
+		r.createAccount("")
 		return
 	}
 
@@ -59,8 +63,39 @@ func (r *repl) chooseAccount() {
 		fmt.Println("none selected")
 		return
 	}
-	accNumber = accNumber - 1
-	err = r.client.SetCurrentAccount(accNumber)
+	r.loadAccount(accNumber - 1)
+}
+
+// setAccount sets the account given inline by number or name as current, or asks for it
+func (r *repl) setAccount() {
+	if r.params == "" {
+		r.chooseAccount()
+		return
+	}
+	accs, err := r.client.ListAccounts()
+	if err != nil {
+		log.Error("failure to choose account", err)
+		return
+	}
+	accNumber := -1
+	if n, err := strconv.Atoi(r.params); err == nil && n > 0 && n <= len(accs) {
+		accNumber = n - 1
+	}
+	for i, name := range accs {
+		if accNumber < 0 && name == r.params {
+			accNumber = i
+		}
+	}
+	if accNumber < 0 {
+		fmt.Println(printPrefix, "No account", r.params)
+		r.fail(setAccountUsage)
+		return
+	}
+	r.loadAccount(accNumber)
+}
+
+func (r *repl) loadAccount(accNumber int) {
+	err := r.client.SetCurrentAccount(accNumber)
 	if err != nil {
 		log.Error("failure to set current account", err)
 		return
@@ -74,9 +109,16 @@ func (r *repl) chooseAccount() {
 
 }
 
-func (r *repl) createAccount() {
+// newAccount creates an account named inline, or asks for its name
+func (r *repl) newAccount() {
+	r.createAccount(r.params)
+}
+
+func (r *repl) createAccount(alias string) {
 	fmt.Println(printPrefix, "Create a new account")
-	alias := inputNotBlank(createAccountMsg)
+	if alias == "" {
+		alias = inputNotBlank(createAccountMsg)
+	}
 
 	ac, err := r.client.CreateAccount(alias)
 	if err != nil {
@@ -93,23 +135,15 @@ func (r *repl) createAccount() {
 
 }
 
-const onesmh = 1000000000000
-
 func coinAmount(val uint64) string {
-	if val >= 1000000000000 {
-		return fmt.Sprintf("%d.%012d SMH", val/onesmh, val%onesmh)
-	} else if val >= 10000000000 {
-		return fmt.Sprintf("0.%012d SMH", val%onesmh)
-	} else {
-		return fmt.Sprint(val, " Smidge")
-	}
+	return common.FormatAmount(val)
 }
 
 // print account info from global state
 func (r *repl) printAccountInfo() {
 	acc, err := r.getCurrent()
 	if err != nil {
-		log.Error("failed to get account", err)
+		r.renderError("failed to get account", err)
 		return
 	}
 
@@ -117,29 +151,20 @@ func (r *repl) printAccountInfo() {
 
 	state, err := r.client.AccountState(address)
 	if err != nil {
-		log.Error("failed to get account info: %v", err)
+		r.renderError("failed to get account info", err)
 		return
 	}
 
-	currBalance := uint64(0)
-	if state.StateCurrent.Balance != nil {
-		currBalance = state.StateCurrent.Balance.Value
-	}
-
-	projectedBalance := uint64(0)
-	if state.StateProjected.Balance != nil {
-		projectedBalance = state.StateProjected.Balance.Value
-	}
-
-	fmt.Println(printPrefix, "Local alias:", acc.Name)
-	fmt.Println(printPrefix, "Address:", address.String())
-	fmt.Println(printPrefix, "Balance:", coinAmount(currBalance)) // currBalance, coinUnitName)
-	fmt.Println(printPrefix, "Nonce:", state.StateCurrent.Counter)
-	fmt.Println(printPrefix, "Projected Balance:", coinAmount(projectedBalance)) // projectedBalance, coinUnitName)
-	fmt.Println(printPrefix, "Projected Nonce:", state.StateProjected.Counter)
-	fmt.Println(printPrefix, "Projected account state includes all pending transactions that haven't been added to the mesh yet.")
-	fmt.Println(printPrefix, fmt.Sprintf("Public key: 0x%s", hex.EncodeToString(acc.PubKey)))
-	fmt.Println(printPrefix, fmt.Sprintf("Private key: 0x%s", hex.EncodeToString(acc.PrivKey)))
+	r.render(output.Account{
+		Name:             acc.Name,
+		Address:          address.String(),
+		Balance:          state.StateCurrent.GetBalance().GetValue(),
+		Nonce:            state.StateCurrent.GetCounter(),
+		ProjectedBalance: state.StateProjected.GetBalance().GetValue(),
+		ProjectedNonce:   state.StateProjected.GetCounter(),
+		PublicKey:        "0x" + hex.EncodeToString(acc.PubKey),
+		PrivateKey:       "0x" + hex.EncodeToString(acc.PrivKey),
+	})
 }
 
 // printAccountRewards prints all rewards awarded to an account
@@ -147,22 +172,17 @@ func (r *repl) printRewards(address gosmtypes.Address) {
 	// todo: request offset and total from user
 	rewards, total, err := r.client.AccountRewards(address, 0, 10000)
 	if err != nil {
-		log.Error("failed to list transactions: %v", err)
+		r.renderError("failed to list rewards", err)
 		return
 	}
-
-	fmt.Println(printPrefix, fmt.Sprintf("Total rewards: %d", total))
-	for _, r := range rewards {
-		printReward(r)
-		fmt.Println(printPrefix, "-----")
-	}
+	r.render(output.NewRewards(rewards, total))
 }
 
 // printAccountRewards prints all rewards awarded to an account
 func (r *repl) printLocalAccountRewards() {
 	acc, err := r.getCurrent()
 	if err != nil {
-		log.Error("failed to get account", err)
+		r.renderError("failed to get account", err)
 		return
 	}
 	r.printRewards(acc.Address())
@@ -170,22 +190,23 @@ func (r *repl) printLocalAccountRewards() {
 
 // printAccountRewards prints all rewards awarded to an account
 func (r *repl) printAnyAccountRewards() {
-	addrStr := inputNotBlank(enterAddressMsg)
-	addr := gosmtypes.HexToAddress(addrStr)
-
+	args, ok := r.parseCommandArgs(r.params, anyRewardsUsage, 1, nil)
+	if !ok {
+		return
+	}
+	if len(args) == 0 {
+		r.printRewards(inputAddress(enterAddressMsg))
+		return
+	}
+	addr, err := common.ParseAddress(args[0])
+	if err != nil {
+		r.fail("invalid address:", err)
+		r.fail(anyRewardsUsage)
+		return
+	}
 	r.printRewards(addr)
 }
 
-func printReward(r *apitypes.Reward) {
-	fmt.Println(printPrefix, "Rewarded on layer:", r.Layer.Number)
-	//fmt.Println(printPrefix, "Rewarded for layer:", r.LayerComputed.Number)
-	fmt.Println(printPrefix, "Layer reward", r.LayerReward.Value, coinUnitName)
-	fmt.Println(printPrefix, "Transaction fees", r.Total.Value-r.LayerReward.Value, coinUnitName)
-	fmt.Println(printPrefix, "Total reward", r.Total.Value, coinUnitName)
-	//fmt.Println(printPrefix, "Smesher id", "0x"+hex.EncodeToString(r.Smesher.Id))
-	fmt.Println(printPrefix, "Rewards account:", gosmtypes.BytesToAddress(r.Coinbase.Address).String())
-}
-
 func (r *repl) getCurrent() (acc *common.LocalAccount, err error) {
 	acc, err = r.client.CurrentAccount()
 	if err != nil {
@@ -202,7 +223,14 @@ func (r *repl) sign() {
 		return
 	}
 
-	msgStr := inputNotBlank(msgSignMsg)
+	args, ok := r.parseCommandArgs(r.params, signUsage, 1, nil)
+	if !ok {
+		return
+	}
+	msgStr := argAt(args, 0)
+	if msgStr == "" {
+		msgStr = inputNotBlank(msgSignMsg)
+	}
 	msg, err := hex.DecodeString(msgStr)
 	if err != nil {
 		log.Error("failed to decode msg hex string: %v", err)
@@ -221,7 +249,11 @@ func (r *repl) textsign() {
 		return
 	}
 
-	msg := inputNotBlank(msgTextSignMsg)
+	// the whole rest of the line is the message
+	msg := r.params
+	if msg == "" {
+		msg = inputNotBlank(msgTextSignMsg)
+	}
 	signature := ed25519.Sign2(acc.PrivKey, []byte(msg))
 
 	fmt.Println(printPrefix, fmt.Sprintf("signature (in hex): %x", signature))
@@ -0,0 +1,119 @@
+package repl
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+)
+
+// Usage of the commands which take inline arguments. Missing arguments are prompted for.
+const (
+	sendCoinUsage       = "usage: send-coin [ADDRESS] [AMOUNT] [--fee N|low|normal|fast] [--gas-limit N]"
+	sendBatchUsage      = "usage: send-batch [FILE] [--fee N|low|normal|fast] [--gas-limit N]"
+	sweepUsage          = "usage: sweep [ADDRESS] [--fee N|low|normal|fast] [--gas-limit N]"
+	consolidateUsage    = "usage: consolidate [--fee N|low|normal|fast] [--gas-limit N]"
+	callAppUsage        = "usage: call-app [APP] [FUNCTION] [HEX_ARGS] [--amount N] [--fee N|low|normal|fast] [--gas-limit N]"
+	spawnAppUsage       = "usage: spawn-app [TEMPLATE] [HEX_ARGS] [--amount N] [--fee N|low|normal|fast] [--gas-limit N]"
+	deployTemplateUsage = "usage: deploy-template [FILE] [--fee N|low|normal|fast] [--gas-limit N]"
+	loadFunctionsUsage  = "usage: load-functions [FILE]"
+	setAccountUsage     = "usage: set [NUMBER|NAME]"
+	newAccountUsage     = "usage: new [NAME]"
+	anyRewardsUsage     = "usage: any-rewards [ADDRESS]"
+	smesherRewardsUsage = "usage: smesher-rewards [SMESHER_ID]"
+	signUsage           = "usage: sign [HEX_MESSAGE]"
+	startSmeshingUsage  = "usage: start-smeshing [DATA_DIR] [SPACE_GB]"
+)
+
+// parseCommandArgs parses the inline arguments of a command. Flags, registered by flags, may come before
+// or after the positional arguments, of which there may be up to maxArgs. On an error, the error and the
+// usage of the command are displayed and false is returned.
+func (r *repl) parseCommandArgs(params, usage string, maxArgs int, flags func(fs *flag.FlagSet)) ([]string, bool) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	if flags != nil {
+		flags(fs)
+	}
+	args, err := parseInterspersed(fs, strings.Fields(params))
+	if err == nil && len(args) > maxArgs {
+		err = fmt.Errorf("unexpected argument %s", args[maxArgs])
+	}
+	if err != nil {
+		if err != flag.ErrHelp {
+			r.fail(err)
+		}
+		fmt.Println(printPrefix, usage)
+		return nil, false
+	}
+	return args, true
+}
+
+// parseInterspersed parses flags found anywhere in args and returns the other arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// argAt returns the positional argument i, or an empty string when it is missing
+func argAt(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// feeArg returns the fee given inline as a number of Smidge or as a fee preset
+func (r *repl) feeArg(s string) (uint64, error) {
+	if f, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return f, nil
+	}
+	for _, p := range common.FeePresets {
+		if p == s {
+			estimate, err := r.client.EstimateFees()
+			if err != nil {
+				return 0, err
+			}
+			return estimate.Preset(s), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid fee %s. Use a number of %s or one of %s", s, coinUnitName, strings.Join(common.FeePresets, ", "))
+}
+
+// transactionFlags are the --fee and --gas-limit flags of the commands which submit transactions
+type transactionFlags struct {
+	fee, gasLimit string
+}
+
+func (f *transactionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.fee, "fee", "", "fee")
+	fs.StringVar(&f.gasLimit, "gas-limit", "", "gas limit")
+}
+
+// feeOrInput returns the fee set with --fee, or else asks for it
+func (r *repl) feeOrInput(f transactionFlags) (uint64, error) {
+	if f.fee != "" {
+		return r.feeArg(f.fee)
+	}
+	return r.inputFee()
+}
+
+// gasLimitOr returns the gas limit set with --gas-limit, or else the gas limit returned by otherwise
+func gasLimitOr(f transactionFlags, otherwise func() (uint64, error)) (uint64, error) {
+	if f.gasLimit != "" {
+		return strconv.ParseUint(f.gasLimit, 10, 64)
+	}
+	return otherwise()
+}
@@ -0,0 +1,147 @@
+package repl
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/log"
+)
+
+// submitBatchTransactions sends the payments listed in a csv file from the current account
+func (r *repl) submitBatchTransactions() {
+	var flags transactionFlags
+	args, ok := r.parseCommandArgs(r.params, sendBatchUsage, 1, flags.register)
+	if !ok {
+		return
+	}
+	fileName := argAt(args, 0)
+	if fileName == "" {
+		fileName = inputNotBlank(batchFileMsg)
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		log.Error("failed to open payments file: %v", err)
+		return
+	}
+	payments, errs := common.ReadPayments(f)
+	f.Close()
+	if len(errs) > 0 {
+		r.fail("Invalid payments file:")
+		for _, err := range errs {
+			fmt.Println(printPrefix, err)
+		}
+		return
+	}
+
+	if !r.canSubmitTransactions() {
+		r.fail("Can't submit a new transaction. Please try again later")
+		return
+	}
+	acc, err := r.getCurrent()
+	if err != nil {
+		log.Error("failed to get account", err)
+		return
+	}
+	srcAddress := acc.Address()
+
+	gas, err := r.feeOrInput(flags)
+	if err != nil {
+		log.Error("invalid transaction fee", err)
+		return
+	}
+	gasLimit, err := gasLimitOr(flags, r.inputGasLimit)
+	if err != nil {
+		log.Error("invalid gas limit", err)
+		return
+	}
+	total, fees, cost, err := common.PaymentsCost(payments, gas)
+	if err != nil {
+		r.fail("Invalid batch:", err)
+		return
+	}
+
+	r.reconcilePendingTransactions(srcAddress)
+	state, err := r.client.AccountState(srcAddress)
+	if err != nil {
+		log.Error("failed to get account info: %v", err)
+		return
+	}
+	projectedBalance := uint64(0)
+	if state.StateProjected.Balance != nil {
+		projectedBalance = state.StateProjected.Balance.Value
+	}
+	nonce, err := r.client.NextNonce(srcAddress)
+	if err != nil {
+		log.Error("failed to get account nonce: %v", err)
+		return
+	}
+
+	fmt.Println(printPrefix, "Batch transactions summary:")
+	fmt.Println(printPrefix, "From:        ", srcAddress.String())
+	fmt.Println(printPrefix, "Payments:    ", len(payments))
+	fmt.Println(printPrefix, "Total amount:", coinAmount(total))
+	fmt.Println(printPrefix, "Total fees:  ", fees, coinUnitName)
+	fmt.Println(printPrefix, "Gas limit:   ", gasLimit)
+	fmt.Println(printPrefix, "Total cost:  ", coinAmount(cost))
+	fmt.Println(printPrefix, "Balance:     ", coinAmount(projectedBalance))
+	fmt.Println(printPrefix, "Nonces:      ", nonce, "to", nonce+uint64(len(payments))-1)
+
+	preflight, err := r.client.PreflightFunds(srcAddress, total, fees, nonce)
+	if err != nil {
+		log.Error("failed to check the batch: %v", err)
+		return
+	}
+	for _, w := range preflight.Warnings {
+		fmt.Println(printPrefix, "Warning:", w)
+	}
+	if preflight.Insufficient {
+		r.fail("Batch not sent")
+		return
+	}
+
+	if !r.confirm(confirmTransactionMsg) {
+		return
+	}
+
+	results := make([]common.PaymentResult, 0, len(payments))
+	failed := false
+	for _, p := range payments {
+		result := common.PaymentResult{Payment: p, Nonce: nonce}
+		if failed {
+			result.State = "Not submitted"
+			results = append(results, result)
+			continue
+		}
+		txState, err := r.transfer(acc, p.Recipient, nonce, p.Amount, gas, gasLimit)
+		if err != nil {
+			// later payments would leave a gap in the nonces, stop here
+			log.Error("failed to submit payment on line %d: %v", p.Line, err)
+			result.State = "Failed: " + err.Error()
+			failed = true
+		} else {
+			result.TxId = "0x" + hex.EncodeToString(txState.Id.Id)
+			result.State = transactionStateDisStringsMap[int32(txState.State.Number())]
+			nonce++
+		}
+		fmt.Println(printPrefix, p.Recipient.String(), coinAmount(p.Amount), result.TxId, result.State)
+		results = append(results, result)
+	}
+
+	resultsFileName := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "_results.csv"
+	out, err := os.Create(resultsFileName)
+	if err != nil {
+		log.Error("failed to create results file: %v", err)
+		return
+	}
+	defer out.Close()
+	if err := common.WritePaymentResults(out, results); err != nil {
+		log.Error("failed to write results file: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, "Results written to", resultsFileName)
+}
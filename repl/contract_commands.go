@@ -0,0 +1,261 @@
+package repl
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"os"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/log"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// contractFlags are the flags of the commands which submit smart contract transactions
+type contractFlags struct {
+	transactionFlags
+	amount string
+}
+
+func (f *contractFlags) register(fs *flag.FlagSet) {
+	f.transactionFlags.register(fs)
+	fs.StringVar(&f.amount, "amount", "", "amount")
+}
+
+// callApp calls a function of an app
+func (r *repl) callApp() {
+	var flags contractFlags
+	args, ok := r.parseCommandArgs(r.params, callAppUsage, 3, flags.register)
+	if !ok {
+		return
+	}
+
+	app, ok := r.addressArg(argAt(args, 0), appAddressMsg, callAppUsage)
+	if !ok {
+		return
+	}
+	function := argAt(args, 1)
+	if function == "" {
+		function = inputNotBlank(functionMsg)
+	}
+	callArgs := argAt(args, 2)
+	if len(args) < 2 {
+		callArgs = inputOptional(callArgsMsg)
+	}
+	packed, err := common.ParseHex(callArgs)
+	if err != nil {
+		r.fail("invalid arguments:", err)
+		return
+	}
+	data, err := common.EncodeAppCall(function, packed)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	if !strings.HasPrefix(function, "0x") {
+		r.functions.Add(function)
+	}
+	r.submitContractTransaction(apitypes.SmartContractTransaction_TRANSACTION_TYPE_APP, app, data, flags)
+}
+
+// spawnApp spawns an app from a template
+func (r *repl) spawnApp() {
+	var flags contractFlags
+	args, ok := r.parseCommandArgs(r.params, spawnAppUsage, 2, flags.register)
+	if !ok {
+		return
+	}
+
+	template, ok := r.addressArg(argAt(args, 0), templateAddressMsg, spawnAppUsage)
+	if !ok {
+		return
+	}
+	ctorArgs := argAt(args, 1)
+	if len(args) < 1 {
+		ctorArgs = inputOptional(callArgsMsg)
+	}
+	data, err := common.ParseHex(ctorArgs)
+	if err != nil {
+		r.fail("invalid arguments:", err)
+		return
+	}
+	r.submitContractTransaction(apitypes.SmartContractTransaction_TRANSACTION_TYPE_APP_SPAWN_APP, template, data, flags)
+}
+
+// deployTemplate deploys the code of a template read from a file
+func (r *repl) deployTemplate() {
+	var flags transactionFlags
+	args, ok := r.parseCommandArgs(r.params, deployTemplateUsage, 1, flags.register)
+	if !ok {
+		return
+	}
+
+	file := argAt(args, 0)
+	if file == "" {
+		file = inputNotBlank(templateFileMsg)
+	}
+	code, err := ioutil.ReadFile(file)
+	if err != nil {
+		r.fail("failed to read the template code:", err)
+		return
+	}
+	if len(code) == 0 {
+		r.fail("The template code file is empty")
+		return
+	}
+	r.submitContractTransaction(apitypes.SmartContractTransaction_TRANSACTION_TYPE_DEPLOY_TEMPLATE, gosmtypes.Address{}, code, contractFlags{transactionFlags: flags})
+}
+
+// loadFunctions reads function names from a file, so that the app calls which call them show their name
+func (r *repl) loadFunctions() {
+	args, ok := r.parseCommandArgs(r.params, loadFunctionsUsage, 1, nil)
+	if !ok {
+		return
+	}
+	file := argAt(args, 0)
+	if file == "" {
+		file = inputNotBlank(functionsFileMsg)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		r.fail("failed to open the functions file:", err)
+		return
+	}
+	defer f.Close()
+	functions, err := common.ReadContractFunctions(f)
+	if err != nil {
+		r.fail("failed to read the functions file:", err)
+		return
+	}
+	for selector, name := range functions {
+		r.functions[selector] = name
+	}
+	fmt.Println(printPrefix, fmt.Sprintf("Loaded %d functions", len(functions)))
+}
+
+// addressArg parses the address given inline, or asks for it with msg when it is missing
+func (r *repl) addressArg(arg, msg, usage string) (gosmtypes.Address, bool) {
+	if arg == "" {
+		return inputAddress(msg), true
+	}
+	address, err := common.ParseAddress(arg)
+	if err != nil {
+		r.fail("invalid address:", err)
+		r.fail(usage)
+		return address, false
+	}
+	return address, true
+}
+
+// submitContractTransaction builds a smart contract transaction from the current account, displays its
+// summary and, once confirmed, signs and submits it
+func (r *repl) submitContractTransaction(t apitypes.SmartContractTransaction_TransactionType, account gosmtypes.Address, data []byte, flags contractFlags) {
+	if !r.canSubmitTransactions() {
+		r.fail("Can't submit a new transaction. Please try again later")
+		return
+	}
+	acc, err := r.getCurrent()
+	if err != nil {
+		log.Error("failed to get account", err)
+		return
+	}
+
+	var amount uint64
+	if flags.amount != "" {
+		if amount, err = common.ParseAmount(flags.amount); err != nil {
+			r.fail("invalid amount:", err)
+			return
+		}
+	}
+	fee, err := r.feeOrInput(flags.transactionFlags)
+	if err != nil {
+		r.fail("invalid transaction fee:", err)
+		return
+	}
+	gasLimit, err := gasLimitOr(flags.transactionFlags, defaultGasLimit)
+	if err != nil {
+		r.fail("invalid gas limit:", err)
+		return
+	}
+	cost, carry := bits.Add64(amount, fee, 0)
+	if carry != 0 {
+		r.fail("Invalid transaction: the amount plus fee is too large")
+		return
+	}
+
+	sender := acc.Address()
+	r.reconcilePendingTransactions(sender)
+	nonce, err := r.client.NextNonce(sender)
+	if err != nil {
+		log.Error("failed to get account nonce: %v", err)
+		return
+	}
+	tx := common.InnerSerializableContractTransaction{
+		Type:         uint32(t),
+		AccountNonce: nonce,
+		Account:      account,
+		GasLimit:     gasLimit,
+		Price:        fee,
+		Amount:       amount,
+		Data:         data,
+	}
+
+	call := tx.Call()
+	fmt.Println(printPrefix, "New transaction summary:")
+	fmt.Println(printPrefix, "Type:      ", call.Kind)
+	fmt.Println(printPrefix, "From:      ", sender.String())
+	if t != apitypes.SmartContractTransaction_TRANSACTION_TYPE_DEPLOY_TEMPLATE {
+		fmt.Println(printPrefix, fmt.Sprintf("%-11s", call.AccountRole+":"), account.String())
+	}
+	if function := call.Function(r.functions); function != "" {
+		fmt.Println(printPrefix, "Function:  ", function)
+		fmt.Println(printPrefix, "Arguments: ", len(call.Args), "bytes")
+	} else {
+		fmt.Println(printPrefix, "Data:      ", len(data), "bytes")
+	}
+	fmt.Println(printPrefix, "Amount:    ", coinAmount(amount))
+	fmt.Println(printPrefix, "Fee:       ", fee, coinUnitName)
+	fmt.Println(printPrefix, "Gas limit: ", gasLimit)
+	fmt.Println(printPrefix, "Total cost:", coinAmount(cost))
+	fmt.Println(printPrefix, "Nonce:     ", nonce)
+
+	preflight, err := r.client.PreflightFunds(sender, amount, fee, nonce)
+	if err != nil {
+		log.Error("failed to check the transaction: %v", err)
+		return
+	}
+	for _, w := range preflight.Warnings {
+		fmt.Println(printPrefix, "Warning:", w)
+	}
+	if preflight.Insufficient {
+		r.fail("Transaction not sent")
+		return
+	}
+
+	if !r.confirm(confirmTransactionMsg) {
+		return
+	}
+	txState, err := r.submitContract(acc, tx)
+	var v *common.PolicyViolation
+	if errors.As(err, &v) {
+		r.printTransferError(err)
+		return
+	}
+	if err != nil {
+		r.fail("Transaction not sent:", err)
+		return
+	}
+
+	fmt.Println(printPrefix, "Transaction submitted.")
+	fmt.Println(printPrefix, fmt.Sprintf("Transaction id: 0x%v", hex.EncodeToString(txState.Id.Id)))
+	fmt.Println(printPrefix, "Transaction state:", transactionStateDisStringsMap[int32(txState.State.Number())])
+
+	if r.scriptDepth == 0 && r.confirm(watchTransactionMsg) {
+		r.watchTransactionId(txState.Id.Id, 1)
+	}
+}
@@ -0,0 +1,121 @@
+package repl
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/log"
+)
+
+// exportQuery holds the options of the export commands
+type exportQuery struct {
+	fileName  string
+	format    string
+	fromLayer uint32
+	toLayer   uint32
+}
+
+// parseExportQuery parses the arguments of an export command. Range bounds are layers or dates,
+// the range defaults to all layers up to the current one.
+func (r *repl) parseExportQuery(cmd string) (*exportQuery, error) {
+	var from, to, format string
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVar(&from, "from", "", "first layer or date")
+	fs.StringVar(&to, "to", "", "last layer or date")
+	fs.StringVar(&format, "format", "", "csv or json")
+	if err := fs.Parse(strings.Fields(r.params)); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return nil, fmt.Errorf("missing output file")
+	}
+
+	q := &exportQuery{fileName: fs.Arg(0)}
+	var err error
+	if q.format, err = common.ExportFormat(q.fileName, format); err != nil {
+		return nil, err
+	}
+	info, err := r.client.GetMeshInfo()
+	if err != nil {
+		return nil, err
+	}
+	q.toLayer = info.CurrentLayer
+	if from != "" {
+		if q.fromLayer, err = common.ParseLayerBound(from, false, info.GenesisTime, info.LayerDuration); err != nil {
+			return nil, err
+		}
+	}
+	if to != "" {
+		if q.toLayer, err = common.ParseLayerBound(to, true, info.GenesisTime, info.LayerDuration); err != nil {
+			return nil, err
+		}
+	}
+	if q.toLayer < q.fromLayer {
+		return nil, fmt.Errorf("the end of the range is before its start")
+	}
+	return q, nil
+}
+
+func (r *repl) exportTransactions() {
+	q, err := r.parseExportQuery("export-txs")
+	if err != nil {
+		r.fail(err)
+		r.fail("usage: export-txs [--from LAYER|DATE] [--to LAYER|DATE] [--format csv|json] <file>")
+		return
+	}
+	records, err := r.client.ExportTransactions(q.fromLayer, q.toLayer)
+	if err != nil {
+		log.Error("failed to export transactions: %v", err)
+		return
+	}
+	r.writeExport(q, len(records), func(f *os.File) error {
+		return common.WriteTxRecords(f, q.format, records)
+	})
+}
+
+func (r *repl) exportRewards() {
+	q, err := r.parseExportQuery("export-rewards")
+	if err != nil {
+		r.fail(err)
+		r.fail("usage: export-rewards [--from LAYER|DATE] [--to LAYER|DATE] [--format csv|json] <file>")
+		return
+	}
+	records, err := r.client.ExportRewards(q.fromLayer, q.toLayer)
+	if err != nil {
+		log.Error("failed to export rewards: %v", err)
+		return
+	}
+	r.writeExport(q, len(records), func(f *os.File) error {
+		return common.WriteRewardRecords(f, q.format, records)
+	})
+}
+
+func (r *repl) writeExport(q *exportQuery, count int, write func(f *os.File) error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if _, err := os.Stat(q.fileName); err == nil {
+		if !r.confirm(overwriteExportMsg) {
+			r.fail("Export not written")
+			return
+		}
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(q.fileName, flags, 0644)
+	if err != nil {
+		log.Error("failed to create export file: %v", err)
+		return
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Error("failed to write export file: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, fmt.Sprintf("Exported %d records from layer %d to layer %d to %s", count, q.fromLayer, q.toLayer, q.fileName))
+}
@@ -2,9 +2,8 @@ package repl
 
 import (
 	"encoding/hex"
-	"fmt"
 
-	"github.com/spacemeshos/CLIWallet/log"
+	"github.com/spacemeshos/CLIWallet/output"
 )
 
 // Outputs the current global state
@@ -12,11 +11,9 @@ func (r *repl) printGlobalState() {
 
 	resp, err := r.client.GlobalStateHash()
 	if err != nil {
-		log.Error("failed to get global state: %v", err)
+		r.renderError("failed to get global state", err)
 		return
 	}
 
-	fmt.Println(printPrefix, "Hash:", "0x"+hex.EncodeToString(resp.RootHash))
-	fmt.Println(printPrefix, "Layer:", resp.Layer.Number)
-
+	r.render(output.GlobalState{RootHash: "0x" + hex.EncodeToString(resp.RootHash), Layer: resp.Layer.GetNumber()})
 }
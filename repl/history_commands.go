@@ -0,0 +1,196 @@
+package repl
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/output"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+const (
+	txsUsage           = "usage: txs [--page N] [--limit N] [--in|--out] [--with ADDRESS] [--min AMOUNT] [--max AMOUNT] [--from LAYER] [--to LAYER] [--sort layer|amount] [--asc]"
+	defaultTxsPageSize = 20
+)
+
+// txsQuery holds the options of the txs command
+type txsQuery struct {
+	page, limit        int
+	in, out, asc       bool
+	with, min, max, by string
+	fromLayer, toLayer uint
+}
+
+func parseTxsQuery(args []string) (*txsQuery, error) {
+	q := &txsQuery{}
+	fs := flag.NewFlagSet("txs", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.IntVar(&q.page, "page", 1, "page to display")
+	fs.IntVar(&q.limit, "limit", defaultTxsPageSize, "transactions per page")
+	fs.BoolVar(&q.in, "in", false, "incoming transactions only")
+	fs.BoolVar(&q.out, "out", false, "outgoing transactions only")
+	fs.StringVar(&q.with, "with", "", "transactions with this address only")
+	fs.StringVar(&q.min, "min", "", "minimum amount")
+	fs.StringVar(&q.max, "max", "", "maximum amount")
+	fs.UintVar(&q.fromLayer, "from", 0, "first layer")
+	fs.UintVar(&q.toLayer, "to", 0, "last layer")
+	fs.StringVar(&q.by, "sort", common.SortByLayer, "sort by layer or amount")
+	fs.BoolVar(&q.asc, "asc", false, "oldest or smallest first")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %s", fs.Arg(0))
+	}
+	if q.page < 1 || q.limit < 1 {
+		return nil, fmt.Errorf("page and limit must be positive")
+	}
+	if q.in && q.out {
+		return nil, fmt.Errorf("--in and --out are exclusive")
+	}
+	if q.by != common.SortByLayer && q.by != common.SortByAmount {
+		return nil, fmt.Errorf("unknown sort order %s", q.by)
+	}
+	if q.toLayer != 0 && q.toLayer < q.fromLayer {
+		return nil, fmt.Errorf("--to layer is before --from layer")
+	}
+	return q, nil
+}
+
+// filter returns the history filter selected by the query
+func (q *txsQuery) filter() (common.TxFilter, error) {
+	f := common.TxFilter{}
+	if q.in {
+		f.Direction = common.TxIn
+	} else if q.out {
+		f.Direction = common.TxOut
+	}
+	if q.with != "" {
+		addr, err := common.ParseAddress(q.with)
+		if err != nil {
+			return f, err
+		}
+		f.Counterparty = &addr
+	}
+	var err error
+	if q.min != "" {
+		if f.MinAmount, err = common.ParseAmount(q.min); err != nil {
+			return f, err
+		}
+	}
+	if q.max != "" {
+		if f.MaxAmount, err = common.ParseAmount(q.max); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// layerRangeIds returns the ids of the transactions in the layer range of the query, or nil when
+// the query has no layer range
+func layerRangeIds(txs []*apitypes.Transaction, layers map[string]uint32, q *txsQuery) map[string]bool {
+	if q.fromLayer == 0 && q.toLayer == 0 {
+		return nil
+	}
+	ids := make(map[string]bool)
+	for _, tx := range txs {
+		layer, ok := layers[string(tx.Id.Id)]
+		if ok && uint(layer) >= q.fromLayer && (q.toLayer == 0 || uint(layer) <= q.toLayer) {
+			ids[string(tx.Id.Id)] = true
+		}
+	}
+	return ids
+}
+
+// rewardCredits returns the rewards of an account as credits of its history txs, whose transactions are in layers
+func (r *repl) rewardCredits(address gosmtypes.Address, txs []*apitypes.Transaction, layers map[string]uint32) ([]common.Credit, error) {
+	rewards, _, err := r.client.AccountRewards(address, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	// a reward is credited at the end of its layer
+	byLayer := make(map[uint32]uint64)
+	for _, reward := range rewards {
+		byLayer[reward.Layer.GetNumber()] += reward.Total.GetValue()
+	}
+	// the history is in layer order: a transaction which wasn't found is in the layer of the one before it
+	txLayers := make([]uint32, len(txs))
+	for i, tx := range txs {
+		if layer, ok := layers[string(tx.Id.Id)]; ok {
+			txLayers[i] = layer
+		} else if i > 0 {
+			txLayers[i] = txLayers[i-1]
+		}
+	}
+	return common.LayerCredits(txLayers, byLayer), nil
+}
+
+// printAccountTransactions displays a page of the mesh transactions of the current account
+func (r *repl) printAccountTransactions() {
+	q, err := parseTxsQuery(strings.Fields(r.params))
+	if err != nil {
+		if err != flag.ErrHelp {
+			r.fail(err)
+		}
+		fmt.Println(printPrefix, txsUsage)
+		return
+	}
+	filter, err := q.filter()
+	if err != nil {
+		r.fail(err)
+		return
+	}
+
+	acc, err := r.getCurrent()
+	if err != nil {
+		r.renderError("failed to get account", err)
+		return
+	}
+
+	txs, total, err := r.client.GetMeshTransactions(acc.Address(), 0, 0)
+	if err != nil {
+		r.renderError("failed to get transactions", err)
+		return
+	}
+	state, err := r.client.AccountState(acc.Address())
+	if err != nil {
+		r.renderError("failed to get account info", err)
+		return
+	}
+	balance := uint64(0)
+	if state.StateCurrent.Balance != nil {
+		balance = state.StateCurrent.Balance.Value
+	}
+	layers, err := r.client.AccountTransactionLayers(acc.Address(), txs)
+	if err != nil {
+		r.renderError("failed to get the layers of the transactions", err)
+		return
+	}
+	filter.Ids = layerRangeIds(txs, layers, q)
+
+	credits, err := r.rewardCredits(acc.Address(), txs, layers)
+	if err != nil {
+		r.renderError("failed to get rewards", err)
+		return
+	}
+
+	entries := filter.Filter(common.TxHistory(acc.Address(), txs, balance, credits))
+	common.SortTxHistory(entries, q.by, !q.asc)
+	page := common.PageTxHistory(entries, q.page, q.limit)
+
+	v := output.Transactions{
+		Total:        total,
+		Matching:     len(entries),
+		Page:         q.page,
+		Pages:        (len(entries) + q.limit - 1) / q.limit,
+		Transactions: make([]output.Transaction, len(page)),
+	}
+	for i, e := range page {
+		v.Transactions[i] = output.NewTransaction(e)
+	}
+	r.render(v)
+}
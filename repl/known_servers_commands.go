@@ -0,0 +1,79 @@
+package repl
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/CLIWallet/output"
+)
+
+const (
+	trustServerUsage  = "usage: trust-server <server> [fingerprint]"
+	forgetServerUsage = "usage: forget-server <server>"
+)
+
+// printKnownServers displays the servers which have a pinned public key
+func (r *repl) printKnownServers() {
+	servers, err := r.client.KnownServers()
+	if err != nil {
+		r.renderError("failed to get the known servers", err)
+		return
+	}
+	r.render(output.NewKnownServers(servers))
+}
+
+// trustServer pins the public key a server presents now, replacing its pinned key. The key is trusted
+// when it has the expected fingerprint, or when the user confirms it. Scripts must give the fingerprint:
+// the confirmation is never assumed.
+func (r *repl) trustServer() {
+	args, ok := r.parseCommandArgs(r.params, trustServerUsage, 2, nil)
+	if !ok {
+		return
+	}
+	server := argAt(args, 0)
+	if server == "" {
+		server = inputNotBlank(serverMsg)
+	}
+	expected := argAt(args, 1)
+	fingerprint, subject, err := r.client.ServerKey(server)
+	if err != nil {
+		r.fail("Failed to get the public key of", server+":", err)
+		return
+	}
+	fmt.Println(printPrefix, "Certificate:", subject)
+	fmt.Println(printPrefix, "Public key:", fingerprint)
+	switch {
+	case expected != "":
+		if expected != fingerprint {
+			r.fail("Not trusted.", server, "presents the public key", fingerprint+", not", expected)
+			return
+		}
+	case r.scriptDepth > 0:
+		r.fail("Not trusted. Give the expected fingerprint in scripts: trust-server", server, fingerprint)
+		return
+	case !r.confirm(fmt.Sprintf("Trust this key for %s? (y/n) ", server)):
+		r.fail("Not trusted")
+		return
+	}
+	if err := r.client.TrustServer(server, fingerprint, subject); err != nil {
+		r.fail("Failed to trust the key:", err)
+		return
+	}
+	fmt.Println(printPrefix, "Pinned the public key of", server)
+}
+
+// forgetServer removes the pinned public key of a server
+func (r *repl) forgetServer() {
+	args, ok := r.parseCommandArgs(r.params, forgetServerUsage, 1, nil)
+	if !ok {
+		return
+	}
+	server := argAt(args, 0)
+	if server == "" {
+		server = inputNotBlank(serverMsg)
+	}
+	if err := r.client.ForgetServer(server); err != nil {
+		r.fail(err)
+		return
+	}
+	fmt.Println(printPrefix, "Forgot the public key of", server+". The next key it presents will be pinned")
+}
@@ -6,16 +6,43 @@ const (
 	enterAddressMsg            = "Enter or paste an address: "
 	txIdMsg                    = "Enter or paste transaction id: "
 	smesherIdMsg               = "Enter or paste a Smesher id: "
-	amountToTransferMsg        = "Enter amount to transfer in Smidge: "
+	amountToTransferMsg        = "Enter amount to transfer (Smidge, or SMH e.g. 2.5SMH): "
+	batchFileMsg               = "Enter payments csv file (address,amount[,memo] rows): "
+	scriptFileMsg              = "Enter script file name: "
+	appAddressMsg              = "Enter or paste app address: "
+	templateAddressMsg         = "Enter or paste template address: "
+	functionMsg                = "Enter function name or 0x selector: "
+	callArgsMsg                = "Enter packed arguments in hex (empty for none): "
+	templateFileMsg            = "Enter template code file: "
+	functionsFileMsg           = "Enter function names file (one name per line): "
 	confirmTransactionMsg      = "Confirm transaction (y/n): "
+	watchTransactionMsg        = "Watch the transaction until it is final? (y/n) "
 	confirmDeleteDataMsg       = "Delete smeshing smeshing data files (y/n)"
+	scheduleLayersMsg          = "Enter the number of layers between payments: "
+	scheduleCronMsg            = "Enter cron expression (minute hour day-of-month month day-of-week): "
+	confirmScheduleMsg         = "Confirm scheduled payment (y/n): "
+	clearDroppedMsg            = "Remove dropped transactions from the journal (y/n) "
 	createAccountMsg           = "Account alias (name): "
-	useDefaultGasMsg           = "Use default transaction fee of 1 Smidge? (y/n) "
+	policyTxLimitMsg           = "Per-transaction limit (empty for none): "
+	policyDailyLimitMsg        = "Daily limit (empty for none): "
+	policyAllowlistMsg         = "Allowed recipients, comma separated (empty for any): "
+	policyBlockedHoursMsg      = "Blocked hours, e.g. 22-6,13 (empty for none): "
+	policyThresholdMsg         = "Require a second password above (empty for never): "
+	keepApprovalPasswordMsg    = "Keep the current second password? (y/n) "
+	newApprovalPasswordMsg     = "New second password: "
+	repeatApprovalPasswordMsg  = "Repeat second password: "
+	approvalPasswordMsg        = "Second password: "
+	confirmPolicyMsg           = "Save spending policy (y/n): "
+	confirmRemovePolicyMsg     = "Remove spending policy (y/n): "
+	overwriteExportMsg         = "The export file already exists. Overwrite it? (y/n) "
 	enterGasPrice              = "Enter transaction fee (Smidge):"
+	useDefaultGasLimitMsg      = "Use default gas limit of 100? (y/n) "
+	enterGasLimitMsg           = "Enter gas limit: "
 	smeshingDatadirMsg         = "Enter data file directory: "
 	smeshingSpaceAllocationMsg = "Enter space allocation (GB): "
 	msgSignMsg                 = "Enter message to sign (in hex): "
 	msgTextSignMsg             = "Enter text message to sign: "
+	serverMsg                  = "Enter server host and port: "
 	coinUnitName               = "Smidge"
 )
 
@@ -1,33 +1,31 @@
 package repl
 
 import (
-	"fmt"
-
-	"github.com/spacemeshos/CLIWallet/log"
+	"github.com/spacemeshos/CLIWallet/output"
 )
 
 func (r *repl) nodeInfo() {
 
 	info, err := r.client.NodeInfo()
 	if err != nil {
-		log.Error("failed to get node info: %v", err)
+		r.renderError("failed to get node info", err)
 		return
 	}
 
-	fmt.Println(printPrefix, "Version:", info.Version)
-	fmt.Println(printPrefix, "Build:", info.Build)
-	fmt.Println(printPrefix, "API server:", r.client.ServerInfo())
-
 	status, err := r.client.NodeStatus()
 	if err != nil {
-		log.Error("failed to get node status: %v", err)
+		r.renderError("failed to get node status", err)
 		return
 	}
 
-	fmt.Println(printPrefix, "Synced:", status.IsSynced)
-	fmt.Println(printPrefix, "Synced layer:", status.SyncedLayer.Number)
-	fmt.Println(printPrefix, "Current layer:", status.TopLayer.Number)
-	fmt.Println(printPrefix, "Verified layer:", status.VerifiedLayer.Number)
-	fmt.Println(printPrefix, "Peers:", status.ConnectedPeers)
-
+	r.render(output.Node{
+		Version:       info.Version,
+		Build:         info.Build,
+		Server:        r.client.ServerInfo(),
+		Synced:        status.IsSynced,
+		SyncedLayer:   status.SyncedLayer.GetNumber(),
+		TopLayer:      status.TopLayer.GetNumber(),
+		VerifiedLayer: status.VerifiedLayer.GetNumber(),
+		Peers:         status.ConnectedPeers,
+	})
 }
@@ -0,0 +1,71 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/log"
+	"github.com/spacemeshos/CLIWallet/output"
+)
+
+// jsonFlag selects JSON output for a single command
+const jsonFlag = "--json"
+
+// stripJSONFlag removes the --json flag from the end of a command line and tells whether it was there.
+// It is only a flag at the end, so that it stays in the text of messages such as the one of text-sign.
+func stripJSONFlag(text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 || fields[len(fields)-1] != jsonFlag {
+		return text, false
+	}
+	return strings.TrimSpace(strings.TrimSuffix(text, jsonFlag)), true
+}
+
+// renderer returns the renderer of the running command
+func (r *repl) renderer() output.Renderer {
+	format := r.format
+	if r.commandFormat != "" {
+		format = r.commandFormat
+	}
+	rd, err := output.New(format, os.Stdout, printPrefix)
+	if err != nil {
+		rd = &output.Text{W: os.Stdout, Prefix: printPrefix}
+	}
+	return rd
+}
+
+// render displays the result of the running command
+func (r *repl) render(v output.View) {
+	if err := r.renderer().Render(v); err != nil {
+		log.Error("failed to display result: %v", err)
+	}
+}
+
+// renderError reports why the running command failed. Text output goes to the log as before.
+func (r *repl) renderError(msg string, err error) {
+	r.failed = true
+	rd := r.renderer()
+	if _, ok := rd.(*output.JSON); !ok {
+		log.Error(msg+": %v", err)
+		return
+	}
+	if err := rd.RenderError(fmt.Errorf("%s: %v", msg, err)); err != nil {
+		log.Error("failed to display error: %v", err)
+	}
+}
+
+// setOutputFormat switches the output of all the commands between text and json
+func (r *repl) setOutputFormat() {
+	format := r.params
+	if format == "" {
+		fmt.Println(printPrefix, "Output format:", r.format)
+		return
+	}
+	if _, err := output.New(format, os.Stdout, printPrefix); err != nil {
+		r.fail(err)
+		return
+	}
+	r.format = format
+	fmt.Println(printPrefix, "Output format set to", format)
+}
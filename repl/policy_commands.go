@@ -0,0 +1,200 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/log"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// transfer submits a coin transfer from acc. When the spending policy of acc requires the
+// second password for the transfer, it is asked for and the transfer is submitted again.
+func (r *repl) transfer(acc *common.LocalAccount, to gosmtypes.Address, nonce, amount, fee, gasLimit uint64) (*apitypes.TransactionState, error) {
+	return r.withApproval(acc, to, amount, func() (*apitypes.TransactionState, error) {
+		return r.client.Transfer(to, nonce, amount, fee, gasLimit, acc.PrivKey)
+	})
+}
+
+// submitContract submits a smart contract transaction from acc, asking for the second password like transfer
+func (r *repl) submitContract(acc *common.LocalAccount, tx common.InnerSerializableContractTransaction) (*apitypes.TransactionState, error) {
+	return r.withApproval(acc, tx.Account, tx.Amount, func() (*apitypes.TransactionState, error) {
+		return r.client.SubmitContractTransaction(tx, acc.PrivKey)
+	})
+}
+
+// withApproval runs send. When the spending policy of acc blocks it for want of the second password,
+// the password is asked for to approve sending amount to to, and send is run again.
+func (r *repl) withApproval(acc *common.LocalAccount, to gosmtypes.Address, amount uint64, send func() (*apitypes.TransactionState, error)) (*apitypes.TransactionState, error) {
+	txState, err := send()
+	var v *common.PolicyViolation
+	if errors.As(err, &v) && v.Rule == common.RuleApproval {
+		fmt.Println(printPrefix, fmt.Sprintf("The spending policy of %s requires the second password: %s", acc.Name, v.Reason))
+		if err := r.client.ApproveTransfer(acc.Address(), to, amount, inputPassword(approvalPasswordMsg)); err != nil {
+			return nil, err
+		}
+		txState, err = send()
+	}
+	return txState, err
+}
+
+// printTransferError explains why a transfer was not submitted
+func (r *repl) printTransferError(err error) {
+	var v *common.PolicyViolation
+	if errors.As(err, &v) {
+		r.fail(fmt.Sprintf("Transfer not sent. The %s rule of the spending policy blocked it: %s", v.Rule, v.Reason))
+		return
+	}
+	log.Error(err.Error())
+}
+
+func (r *repl) printSpendingPolicy() {
+	acc, err := r.getCurrent()
+	if err != nil {
+		log.Error("failed to get account", err)
+		return
+	}
+	p, err := r.client.SpendingPolicy(acc.Address())
+	if err != nil {
+		log.Error("failed to get spending policy: %v", err)
+		return
+	}
+	if p == nil {
+		fmt.Println(printPrefix, fmt.Sprintf("%s has no spending policy", acc.Name))
+		return
+	}
+	fmt.Println(printPrefix, fmt.Sprintf("Spending policy of %s:", acc.Name))
+	for _, rule := range p.Describe() {
+		fmt.Println(printPrefix, rule)
+	}
+	if spent, err := r.client.SpentToday(acc.Address()); err == nil {
+		fmt.Println(printPrefix, "Sent today:", coinAmount(spent))
+	}
+}
+
+// checkApprovalPassword asks for the second password of a policy, if it has one
+func checkApprovalPassword(p *common.SpendingPolicy) bool {
+	if p == nil || !p.HasApprovalPassword() {
+		return true
+	}
+	if !p.CheckApprovalPassword(inputPassword(approvalPasswordMsg)) {
+		fmt.Println(printPrefix, "Wrong second password")
+		return false
+	}
+	return true
+}
+
+func (r *repl) setSpendingPolicy() {
+	acc, err := r.getCurrent()
+	if err != nil {
+		log.Error("failed to get account", err)
+		return
+	}
+	current, err := r.client.SpendingPolicy(acc.Address())
+	if err != nil {
+		log.Error("failed to get spending policy: %v", err)
+		return
+	}
+	if !checkApprovalPassword(current) {
+		return
+	}
+
+	p := common.SpendingPolicy{Account: acc.Address().String()}
+	if p.TxLimit, err = inputOptionalAmount(policyTxLimitMsg); err != nil {
+		log.Error("invalid amount: %v", err)
+		return
+	}
+	if p.DailyLimit, err = inputOptionalAmount(policyDailyLimitMsg); err != nil {
+		log.Error("invalid amount: %v", err)
+		return
+	}
+	if list := inputOptional(policyAllowlistMsg); list != "" {
+		for _, s := range strings.Split(list, ",") {
+			addr, err := common.ParseAddress(s)
+			if err != nil {
+				log.Error("invalid address %s: %v", s, err)
+				return
+			}
+			p.Allowlist = append(p.Allowlist, addr.String())
+		}
+	}
+	if p.BlockedHours, err = common.ParseHours(inputOptional(policyBlockedHoursMsg)); err != nil {
+		log.Error("invalid hours: %v", err)
+		return
+	}
+	threshold := inputOptional(policyThresholdMsg)
+	if threshold != "" {
+		if p.ApprovalThreshold, err = common.ParseAmount(threshold); err != nil {
+			log.Error("invalid amount: %v", err)
+			return
+		}
+		if current != nil && current.HasApprovalPassword() && r.confirm(keepApprovalPasswordMsg) {
+			p.ApprovalSalt, p.ApprovalHash = current.ApprovalSalt, current.ApprovalHash
+		} else {
+			password := inputPassword(newApprovalPasswordMsg)
+			if password == "" || password != inputPassword(repeatApprovalPasswordMsg) {
+				fmt.Println(printPrefix, "The passwords are empty or don't match")
+				return
+			}
+			if err := p.SetApprovalPassword(password); err != nil {
+				log.Error("failed to set second password: %v", err)
+				return
+			}
+		}
+	}
+
+	rules := p.Describe()
+	if len(rules) == 0 {
+		fmt.Println(printPrefix, "The policy has no rules. Use policy-remove to remove a policy")
+		return
+	}
+	fmt.Println(printPrefix, fmt.Sprintf("New spending policy of %s:", acc.Name))
+	for _, rule := range rules {
+		fmt.Println(printPrefix, rule)
+	}
+	if !r.confirm(confirmPolicyMsg) {
+		return
+	}
+	if err := r.client.SetSpendingPolicy(p); err != nil {
+		log.Error("failed to save spending policy: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, "Spending policy saved")
+}
+
+func (r *repl) removeSpendingPolicy() {
+	acc, err := r.getCurrent()
+	if err != nil {
+		log.Error("failed to get account", err)
+		return
+	}
+	current, err := r.client.SpendingPolicy(acc.Address())
+	if err != nil {
+		log.Error("failed to get spending policy: %v", err)
+		return
+	}
+	if current == nil {
+		fmt.Println(printPrefix, fmt.Sprintf("%s has no spending policy", acc.Name))
+		return
+	}
+	if !checkApprovalPassword(current) || !r.confirm(confirmRemovePolicyMsg) {
+		return
+	}
+	if err := r.client.RemoveSpendingPolicy(acc.Address()); err != nil {
+		log.Error("failed to remove spending policy: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, "Spending policy removed")
+}
+
+// inputOptionalAmount asks for an amount, returning 0 for a blank input
+func inputOptionalAmount(msg string) (uint64, error) {
+	s := inputOptional(msg)
+	if s == "" {
+		return 0, nil
+	}
+	return common.ParseAmount(s)
+}
@@ -0,0 +1,108 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spacemeshos/CLIWallet/common"
+)
+
+const profileUsage = "usage: profile [NAME]"
+
+// defaultFee returns the fee preset or amount of the profile, used when a command isn't given a fee
+func (r *repl) defaultFee() string {
+	if r.profile == nil || r.profile.Fee == "" {
+		return common.FeeNormal
+	}
+	return r.profile.Fee
+}
+
+// switchProfile lists the profiles of the config file, or connects to the servers of one of them.
+// An open wallet stays open, and its scheduled payments pause while the wallet reconnects.
+func (r *repl) switchProfile() {
+	args, ok := r.parseCommandArgs(r.params, profileUsage, 1, nil)
+	if !ok {
+		return
+	}
+	if r.config == nil {
+		r.fail("No config file")
+		return
+	}
+	if len(args) == 0 {
+		r.printProfiles()
+		return
+	}
+
+	p, err := r.config.Profile(args[0])
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	servers := strings.Join(p.Servers, ", ")
+	fmt.Println(printPrefix, fmt.Sprintf("Connecting to %s...", servers))
+	if r.clientOpen {
+		// the scheduler sends no payment while the servers change
+		r.stopScheduler()
+		defer r.startScheduler()
+	}
+	if err := r.client.Reconnect(p.Connection()); err != nil {
+		r.fail(fmt.Sprintf("Failed to connect to %s: %v. Still using profile %s", servers, err, r.profileName()))
+		return
+	}
+	if err := r.client.Echo(); err != nil {
+		fmt.Println(printPrefix, fmt.Sprintf("Warning: %s doesn't answer: %v", servers, err))
+	}
+	dir := p.WalletDir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	r.client.SetWorkingDirectory(dir)
+	r.client.SetTimeout(p.Timeout)
+	if err := common.SetAmountUnits(p.Units); err != nil {
+		r.fail(err)
+	}
+	r.profile = p
+
+	fmt.Println(printPrefix, "Using profile", p.Name)
+	fmt.Println(printPrefix, "API server:", r.client.ServerInfo())
+	if r.clientOpen {
+		fmt.Println(printPrefix, "The open wallet stays open. Its pending transactions and scheduled payments now go to", servers)
+	} else if p.WalletPath() != "" {
+		fmt.Println(printPrefix, "The wallet of this profile is", p.WalletPath(), "- use open-wallet to open it")
+	}
+}
+
+func (r *repl) profileName() string {
+	if r.profile == nil {
+		return "from the command line"
+	}
+	return r.profile.Name
+}
+
+func (r *repl) printProfiles() {
+	if r.config.File != "" {
+		fmt.Println(printPrefix, "Config file:", r.config.File)
+	}
+	for _, name := range r.config.Names() {
+		p, _ := r.config.Profile(name)
+		current := " "
+		if r.profile != nil && r.profile.Name == name {
+			current = "*"
+		}
+		security := "insecure"
+		if p.Secure {
+			security = "secure"
+		}
+		if p.TLS.ClientCert != "" {
+			security += ", client certificate"
+		}
+		if p.Pin {
+			security += ", pinned keys"
+		}
+		if p.Auth.IsSet() {
+			security += ", token"
+		}
+		fmt.Println(printPrefix, fmt.Sprintf("%s %-12s %s (%s), fee: %s, units: %s", current, name, strings.Join(p.Servers, ", "), security, p.Fee, p.Units))
+	}
+}
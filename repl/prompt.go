@@ -1,12 +1,18 @@
 package repl
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/c-bata/go-prompt"
+	"github.com/spacemeshos/CLIWallet/common"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 var emptyComplete = func(prompt.Document) []prompt.Suggest { return []prompt.Suggest{} }
@@ -98,3 +104,54 @@ func inputNotBlank(msg string) string {
 
 	return input
 }
+
+// executes prompt accepting a blank input
+func inputOptional(msg string) string {
+	return strings.TrimSpace(prompt.Input(prefix+msg,
+		emptyComplete,
+		prompt.OptionPrefixTextColor(prompt.LightGray)))
+}
+
+// reads a password without echoing it
+func inputPassword(msg string) string {
+	fmt.Print(prefix + msg)
+	b, err := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// executes prompt waiting for a valid account address
+func inputAddress(msg string) gosmtypes.Address {
+	for {
+		input := inputNotBlank(msg)
+		addr, err := common.ParseAddress(input)
+		if err != nil {
+			fmt.Println(printPrefix, "invalid address:", err)
+			continue
+		}
+		if !common.IsChecksummed(input) {
+			fmt.Println(printPrefix, "Address entered without checksum. Checksummed address:", addr.String())
+		}
+		return addr
+	}
+}
+
+// interruptContext returns a context which is cancelled when the user presses Ctrl-C,
+// so that a long running command can be stopped without quitting the CLI
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sig)
+	}()
+	return ctx, cancel
+}
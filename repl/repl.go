@@ -1,12 +1,17 @@
 package repl
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/spacemeshos/CLIWallet/client"
 	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/config"
 	"github.com/spacemeshos/CLIWallet/log"
+	"github.com/spacemeshos/CLIWallet/output"
 	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"github.com/spacemeshos/ed25519"
 	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
@@ -23,6 +28,8 @@ const (
 // TestMode variable used for check if unit test is running
 var TestMode = false
 
+const schedulerInterval = 30 * time.Second
+
 type command struct {
 	text        string
 	description string
@@ -30,10 +37,20 @@ type command struct {
 }
 
 type repl struct {
-	commands   []command
-	client     Client
-	clientOpen bool
-	input      string
+	commands      []command
+	client        Client
+	clientOpen    bool
+	input         string
+	params        string // inline arguments of the running command
+	failed        bool   // the running command failed
+	assumeYes     bool   // confirmations are answered yes, set by the header of the running script
+	scriptDepth   int    // nesting of the running scripts
+	config        *config.Config
+	profile       *config.Profile
+	format        string // output format of the session
+	commandFormat string // output format of the running command, when set with --json
+	schedulerStop func()
+	functions     common.ContractFunctions // names of the app functions, by selector
 }
 
 // Client interface to REPL clients.
@@ -54,6 +71,14 @@ type Client interface {
 
 	// Local config
 	ServerInfo() string
+	Reconnect(options client.ConnectionOptions) error
+	SetWorkingDirectory(dir string)
+	SetTimeout(timeout time.Duration)
+	SetContext(ctx context.Context)
+	KnownServers() ([]common.KnownServer, error)
+	ServerKey(server string) (fingerprint, subject string, err error)
+	TrustServer(server, fingerprint, subject string) error
+	ForgetServer(server string) error
 
 	// Node service
 	NodeStatus() (*apitypes.NodeStatus, error)
@@ -62,12 +87,47 @@ type Client interface {
 
 	// Mesh service
 	GetMeshTransactions(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.Transaction, uint32, error)
+	AccountMeshTransactions(address gosmtypes.Address, minLayer uint32, offset uint32, maxResults uint32) ([]*apitypes.Transaction, uint32, error)
 	GetMeshActivations(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.Activation, uint32, error)
 	GetMeshInfo() (*common.NetInfo, error)
+	GetLayers(startLayer uint32, endLayer uint32) ([]*apitypes.Layer, error)
+	EstimateFees() (*common.FeeEstimate, error)
+	FindTransactionLayer(txId []byte, startLayer uint32, endLayer uint32) (uint32, bool, error)
 
 	// Transaction service
 	Transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*apitypes.TransactionState, error)
+	SubmitContractTransaction(tx common.InnerSerializableContractTransaction, key ed25519.PrivateKey) (*apitypes.TransactionState, error)
 	TransactionState(txId []byte, includeTx bool) (*apitypes.TransactionState, *apitypes.Transaction, error)
+	PreflightTransfer(sender, recipient gosmtypes.Address, amount, fee, nonce uint64) (*common.PreflightResult, error)
+	PreflightFunds(sender gosmtypes.Address, amount, fee, nonce uint64) (*common.PreflightResult, error)
+	WatchTransactionState(ctx context.Context, txId []byte, update func(*apitypes.TransactionState, *apitypes.Transaction) bool) error
+
+	// Local transactions journal
+	NextNonce(address gosmtypes.Address) (uint64, error)
+	PendingTransactions(address gosmtypes.Address) ([]common.PendingTransaction, error)
+	ReconcilePendingTransactions(address gosmtypes.Address) (confirmed, dropped []common.PendingTransaction, err error)
+	ClearDroppedTransactions(address gosmtypes.Address) error
+	PendingTransaction(address gosmtypes.Address, txId []byte) (*common.PendingTransaction, error)
+	ReplaceTransaction(txId []byte, fee uint64, cancel bool) (*apitypes.TransactionState, error)
+
+	// Scheduled payments
+	Schedules() ([]common.ScheduledPayment, error)
+	AddSchedule(s common.ScheduledPayment) (*common.ScheduledPayment, error)
+	RemoveSchedule(id string) error
+	ScheduleLog() ([]common.ScheduleExecution, error)
+	RunDueSchedules() ([]common.ScheduleExecution, error)
+	StartScheduler(interval time.Duration, report func(common.ScheduleExecution)) (stop func())
+
+	// Spending policies
+	SpendingPolicy(address gosmtypes.Address) (*common.SpendingPolicy, error)
+	SetSpendingPolicy(p common.SpendingPolicy) error
+	RemoveSpendingPolicy(address gosmtypes.Address) error
+	SpentToday(address gosmtypes.Address) (uint64, error)
+	ApproveTransfer(sender, recipient gosmtypes.Address, amount uint64, password string) error
+
+	// Accounting exports
+	ExportTransactions(fromLayer uint32, toLayer uint32) ([]common.TxRecord, error)
+	ExportRewards(fromLayer uint32, toLayer uint32) ([]common.RewardRecord, error)
 
 	// Smesher service
 	GetSmesherId() ([]byte, error)
@@ -84,6 +144,8 @@ type Client interface {
 	AccountState(address gosmtypes.Address) (*apitypes.Account, error)
 	AccountRewards(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.Reward, uint32, error)
 	AccountTransactionsReceipts(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.TransactionReceipt, uint32, error)
+	AccountTransactionLayers(address gosmtypes.Address, txs []*apitypes.Transaction) (map[string]uint32, error)
+	TransactionReceipt(address gosmtypes.Address, txId []byte) (*apitypes.TransactionReceipt, error)
 	GlobalStateHash() (*apitypes.GlobalStateHash, error)
 	SmesherRewards(smesherId []byte, offset uint32, maxResults uint32) ([]*apitypes.Reward, uint32, error)
 }
@@ -96,26 +158,54 @@ func (r *repl) initializeCommands() {
 		{"create-wallet", "Create a wallet", r.createWallet},
 		// transactions
 
-		{"tx-status", "Display a transaction status", r.printTransactionStatus},
+		{"tx-status", "Display a transaction status: tx-status [TX_ID]", r.printTransactionStatus},
+		{"watch-tx", "Follow a transaction until it is final", r.watchTransaction},
+		{"load-functions", "Load app function names, one per line, to show the function of app calls: load-functions [FILE]", r.loadFunctions},
 	}
 	if r.clientOpen {
 		accountCommands = []command{
 			// accounts
 			{"close-wallet", "Close current wallet", r.closeWallet},
 			{"wallet", "Display wallet info", r.walletInfo},
-			{"new", "Create a new account (key pair) and set as current", r.createAccount},
-			{"set", "Set one of the previously created accounts as current", r.chooseAccount},
+			{"new", "Create a new account (key pair) and set as current: new [NAME]", r.newAccount},
+			{"set", "Set one of the previously created accounts as current: set [NUMBER|NAME]", r.setAccount},
 			{"info", "Display the current account info", r.printAccountInfo},
 			{"rewards", "Display all rewards awarded to the current account", r.printLocalAccountRewards},
-			{"sign", "Sign a hex message with the current account private key", r.sign},
-			{"text-sign", "Sign a text message with the current account private key", r.textsign},
-
-			{"any-rewards", "Display all rewards for any account", r.printAnyAccountRewards},
-			{"send-coin", "Transfer coins from current account to another account", r.submitCoinTransaction},
+			{"sign", "Sign a hex message with the current account private key: sign [HEX_MESSAGE]", r.sign},
+			{"text-sign", "Sign a text message with the current account private key: text-sign [TEXT]", r.textsign},
+
+			{"any-rewards", "Display all rewards for any account: any-rewards [ADDRESS]", r.printAnyAccountRewards},
+			{"send-coin", "Transfer coins from current account to another account: send-coin [ADDRESS] [AMOUNT] [--fee N|low|normal|fast] [--gas-limit N]", r.submitCoinTransaction},
+			{"send-batch", "Transfer coins from current account to the recipients listed in a csv file: send-batch [FILE] [--fee N|low|normal|fast] [--gas-limit N]", r.submitBatchTransactions},
+			{"sweep", "Transfer the whole balance of the current account to another account: sweep [ADDRESS] [--fee N|low|normal|fast] [--gas-limit N]", r.sweepAccount},
+			{"consolidate", "Transfer the balances of all the wallet accounts into one of them: consolidate [--fee N|low|normal|fast] [--gas-limit N]", r.consolidateAccounts},
+			{"call-app", "Call a function of an app from the current account: call-app [APP] [FUNCTION] [HEX_ARGS] [--amount N] [--fee N|low|normal|fast] [--gas-limit N]", r.callApp},
+			{"spawn-app", "Spawn an app from a template: spawn-app [TEMPLATE] [HEX_ARGS] [--amount N] [--fee N|low|normal|fast] [--gas-limit N]", r.spawnApp},
+			{"deploy-template", "Deploy the template code of a file: deploy-template [FILE] [--fee N|low|normal|fast] [--gas-limit N]", r.deployTemplate},
 			// transactions
 
-			{"tx-status", "Display a transaction status", r.printTransactionStatus},
-			{"txs", "Display all outgoing and incoming transactions for the current account that are on the mesh", r.printAccountTransactions},
+			{"tx-status", "Display a transaction status: tx-status [TX_ID]", r.printTransactionStatus},
+			{"watch-tx", "Follow a transaction until it is final", r.watchTransaction},
+			{"load-functions", "Load app function names, one per line, to show the function of app calls: load-functions [FILE]", r.loadFunctions},
+			{"txs", "Display the mesh transactions of the current account. Filter, sort and page with flags, see txs --help", r.printAccountTransactions},
+			{"receipts", "Display the transaction receipts of the current account", r.printAccountReceipts},
+			{"pending", "Display the transactions sent from the current account which are not processed yet", r.printPendingTransactions},
+			{"speed-up", "Resend a pending transaction with a higher fee", r.speedUpTransaction},
+			{"cancel", "Cancel a pending transaction by replacing it with a zero value transaction to self", r.cancelTransaction},
+			{"export-txs", "Write the transactions of all the wallet accounts to a csv or json file", r.exportTransactions},
+			{"export-rewards", "Write the rewards of all the wallet accounts to a csv or json file", r.exportRewards},
+
+			// spending policies
+			{"policy-set", "Set the spending policy of the current account", r.setSpendingPolicy},
+			{"policy-remove", "Remove the spending policy of the current account", r.removeSpendingPolicy},
+			{"policy", "Display the spending policy of the current account", r.printSpendingPolicy},
+
+			// scheduled payments
+			{"schedule-add", "Schedule a recurring payment from the current account", r.addSchedule},
+			{"schedule-remove", "Remove a scheduled payment", r.removeSchedule},
+			{"schedule-log", "Display the executions of scheduled payments", r.printScheduleLog},
+			{"schedule-run", "Run the scheduled payments which are due now", r.runSchedules},
+			{"schedules", "Display the scheduled payments", r.printSchedules},
 		}
 	}
 
@@ -130,11 +220,11 @@ func (r *repl) initializeCommands() {
 		// smeshing - rewards ops
 		{"print-rewards-account", "Display the currently set smesher's rewards account", r.printCoinbase},
 		{"set-rewards-account", "Set current account as the node smesher's rewards account", r.setCoinbase},
-		{"smesher-rewards", "Display rewards for a smesher", r.printSmesherRewards},
+		{"smesher-rewards", "Display rewards for a smesher: smesher-rewards [SMESHER_ID]", r.printSmesherRewards},
 
 		// smeshing - smesher ops
 		{"smesher-id", "Display the smesher's current smesher id", r.printSmesherId},
-		{"start-smeshing", "Start smeshing using the current account as the rewards account", r.startSmeshing},
+		{"start-smeshing", "Start smeshing using the current account as the rewards account: start-smeshing [DATA_DIR] [SPACE_GB]", r.startSmeshing},
 		{"stop-smeshing", "Stop smeshing", r.stopSmeshing},
 
 		{"is-smeshing", "Display the proof of space status", r.printIsSmeshing},
@@ -146,17 +236,40 @@ func (r *repl) initializeCommands() {
 		// debug commands
 		{"dbg-all-accounts", "Display all mesh accounts", r.printAllAccounts},
 
+		{"profile", "Display the network profiles, or switch to one: profile [NAME]", r.switchProfile},
+		{"known-servers", "Display the servers which have a pinned public key", r.printKnownServers},
+		{"trust-server", "Pin the public key a server presents, replacing its pinned key: trust-server <server> [fingerprint]", r.trustServer},
+		{"forget-server", "Remove the pinned public key of a server: forget-server <server>", r.forgetServer},
+		{"source", "Run the commands of a script file: source <file>", r.sourceScript},
+		{"output", "Display or set the output format: output text|json. Add --json to a command for json output once", r.setOutputFormat},
+
 		{"quit", "Quit the CLI", r.quit},
 	}
 	r.commands = append(accountCommands, otherCommands...)
 }
 
+// Settings are the settings of the REPL from the command line and the config file
+type Settings struct {
+	Format  string          // output format
+	Config  *config.Config  // profiles the profile command switches between
+	Profile *config.Profile // profile the client is connected with
+}
+
+func newRepl(c Client, s Settings) *repl {
+	r := &repl{client: c, format: s.Format, config: s.Config, profile: s.Profile, functions: make(common.ContractFunctions)}
+	if r.format == "" {
+		r.format = output.FormatText
+	}
+	r.clientOpen = c.IsOpen()
+	return r
+}
+
 // Start starts REPL.
-func Start(c Client) {
+func Start(c Client, s Settings) {
 	if !TestMode {
-		r := &repl{client: c}
-		r.clientOpen = c.IsOpen()
+		r := newRepl(c, s)
 		r.initializeCommands()
+		r.startScheduler()
 
 		runPrompt(r.executor, r.completer, r.firstTime, uint16(len(r.commands)))
 	} else {
@@ -167,16 +280,29 @@ func Start(c Client) {
 }
 
 func (r *repl) executor(text string) {
-	for _, c := range r.commands {
-		if len(text) >= len(c.text) && text[:len(c.text)] == c.text {
+	text, asJSON := stripJSONFlag(strings.TrimSpace(text))
+	r.failed = false
+	for i, c := range r.commands {
+		// a command matches whole words only, so that set doesn't run for set-rewards-account
+		if text == c.text || strings.HasPrefix(text, c.text+" ") {
 			r.input = text
+			r.params = r.commandLineParams(i, text)
+			r.commandFormat = ""
+			if asJSON {
+				r.commandFormat = output.FormatJSON
+			}
 			//log.Debug(userExecutingCommandMsg, c.text)
+			// Ctrl-C cancels the calls of the command to the API server
+			ctx, cancel := interruptContext()
+			r.client.SetContext(ctx)
 			c.fn()
+			r.client.SetContext(nil)
+			cancel()
 			return
 		}
 	}
 
-	fmt.Println(printPrefix, "invalid command.")
+	r.fail("invalid command.")
 }
 
 func (r *repl) completer(in prompt.Document) []prompt.Suggest {
@@ -193,9 +319,10 @@ func (r *repl) completer(in prompt.Document) []prompt.Suggest {
 	return prompt.FilterHasPrefix(suggets, in.GetWordBeforeCursor(), true)
 }
 
+// commandLineParams returns the inline arguments which follow command idx in input
 func (r *repl) commandLineParams(idx int, input string) string {
 	c := r.commands[idx]
-	params := strings.Replace(input, c.text, "", -1)
+	params := strings.TrimPrefix(input, c.text)
 
 	return strings.TrimSpace(params)
 }
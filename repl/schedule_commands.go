@@ -0,0 +1,170 @@
+package repl
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/log"
+)
+
+var scheduleKinds = []string{
+	"Every number of layers",
+	"At every epoch boundary",
+	"At wall clock times (cron expression)",
+}
+
+func (r *repl) addSchedule() {
+	acc, err := r.getCurrent()
+	if err != nil {
+		log.Error("failed to get account", err)
+		return
+	}
+
+	fmt.Println(printPrefix, "Schedule a recurring payment from", acc.Name)
+	s := common.ScheduledPayment{Account: acc.Address().String()}
+	s.Recipient = inputAddress(destAddressMsg).String()
+	if s.Amount, err = common.ParseAmount(inputNotBlank(amountToTransferMsg)); err != nil {
+		log.Error("invalid amount: %v", err)
+		return
+	}
+	if s.Fee, err = r.inputFee(); err != nil {
+		log.Error("invalid transaction fee", err)
+		return
+	}
+
+	fmt.Println(printPrefix, "Choose when the payment runs:")
+	switch multipleChoice(scheduleKinds) {
+	case 1:
+		s.Kind = common.ScheduleEveryLayers
+		layers, err := strconv.ParseUint(inputNotBlank(scheduleLayersMsg), 10, 32)
+		if err != nil {
+			log.Error("invalid number of layers: %v", err)
+			return
+		}
+		s.Layers = uint32(layers)
+	case 2:
+		s.Kind = common.ScheduleEveryEpoch
+	case 3:
+		s.Kind = common.ScheduleCron
+		s.Cron = inputNotBlank(scheduleCronMsg)
+	default:
+		return
+	}
+	if err := s.Validate(); err != nil {
+		log.Error("invalid schedule: %v", err)
+		return
+	}
+
+	fmt.Println(printPrefix, "New scheduled payment summary:")
+	printSchedule(s)
+	if !r.confirm(confirmScheduleMsg) {
+		return
+	}
+	added, err := r.client.AddSchedule(s)
+	if err != nil {
+		log.Error("failed to add schedule: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, "Scheduled payment added with id", added.Id)
+}
+
+func (r *repl) printSchedules() {
+	schedules, err := r.client.Schedules()
+	if err != nil {
+		log.Error("failed to list schedules: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, fmt.Sprintf("Scheduled payments: %d", len(schedules)))
+	for _, s := range schedules {
+		printSchedule(s)
+		fmt.Println(printPrefix, "-----")
+	}
+}
+
+func (r *repl) removeSchedule() {
+	schedules, err := r.client.Schedules()
+	if err != nil {
+		log.Error("failed to list schedules: %v", err)
+		return
+	}
+	if len(schedules) == 0 {
+		fmt.Println(printPrefix, "No scheduled payments")
+		return
+	}
+	names := make([]string, len(schedules))
+	for i, s := range schedules {
+		names[i] = fmt.Sprintf("%s: %s to %s %s", s.Id, coinAmount(s.Amount), s.Recipient, s.Describe())
+	}
+	fmt.Println(printPrefix, "Choose a scheduled payment to remove:")
+	n := multipleChoice(names)
+	if n == 0 {
+		return
+	}
+	if err := r.client.RemoveSchedule(schedules[n-1].Id); err != nil {
+		log.Error("failed to remove schedule: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, "Scheduled payment removed")
+}
+
+func (r *repl) printScheduleLog() {
+	executions, err := r.client.ScheduleLog()
+	if err != nil {
+		log.Error("failed to read schedule log: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, fmt.Sprintf("Scheduled payment executions: %d", len(executions)))
+	for _, e := range executions {
+		printScheduleExecution(e)
+	}
+}
+
+func (r *repl) runSchedules() {
+	executions, err := r.client.RunDueSchedules()
+	if err != nil {
+		log.Error("failed to run scheduled payments: %v", err)
+		return
+	}
+	if len(executions) == 0 {
+		fmt.Println(printPrefix, "No scheduled payments are due")
+	}
+	for _, e := range executions {
+		printScheduleExecution(e)
+	}
+}
+
+// startScheduler runs the scheduled payments in the background while the wallet is open
+func (r *repl) startScheduler() {
+	r.stopScheduler()
+	if r.clientOpen {
+		r.schedulerStop = r.client.StartScheduler(schedulerInterval, printScheduleExecution)
+	}
+}
+
+func (r *repl) stopScheduler() {
+	if r.schedulerStop != nil {
+		r.schedulerStop()
+		r.schedulerStop = nil
+	}
+}
+
+func printSchedule(s common.ScheduledPayment) {
+	if s.Id != "" {
+		fmt.Println(printPrefix, "Id:    ", s.Id)
+	}
+	fmt.Println(printPrefix, "From:  ", s.Account)
+	fmt.Println(printPrefix, "To:    ", s.Recipient)
+	fmt.Println(printPrefix, "Amount:", coinAmount(s.Amount))
+	fmt.Println(printPrefix, "Fee:   ", s.Fee, coinUnitName)
+	fmt.Println(printPrefix, "Runs:  ", s.Describe())
+}
+
+func printScheduleExecution(e common.ScheduleExecution) {
+	result := "transaction id: " + e.TxId
+	if e.Error != "" {
+		result = "failed: " + e.Error
+	}
+	fmt.Println(printPrefix, e.Time.Local().Format("2006-01-02 15:04:05"), "layer", e.Layer, "schedule", e.ScheduleId,
+		coinAmount(e.Amount), "to", e.Recipient, result)
+}
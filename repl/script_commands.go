@@ -0,0 +1,104 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/log"
+)
+
+const (
+	sourceUsage = "usage: source <file>"
+	// maximum nesting of scripts which source other scripts
+	maxScriptDepth = 8
+)
+
+// fail displays why the running command failed, so that a script running it stops
+func (r *repl) fail(a ...interface{}) {
+	r.failed = true
+	fmt.Println(append([]interface{}{printPrefix}, a...)...)
+}
+
+// confirm asks to confirm an operation. Scripts with --yes in their header confirm without asking.
+func (r *repl) confirm(msg string) bool {
+	if r.assumeYes {
+		fmt.Println(printPrefix, msg+"y ("+common.ScriptYes+")")
+		return true
+	}
+	return yesOrNoQuestion(msg) == "y"
+}
+
+// execute runs a command line and returns false if the command failed
+func (r *repl) execute(text string) bool {
+	errors := log.ErrorCount()
+	r.executor(text)
+	return !r.failed && log.ErrorCount() == errors
+}
+
+// runScript runs the commands of a script file, displaying each command before it runs.
+// It stops at the first failed command unless the script header has --keep-going.
+func (r *repl) runScript(fileName string) error {
+	if r.scriptDepth >= maxScriptDepth {
+		return fmt.Errorf("scripts are nested more than %d times", maxScriptDepth)
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	s, err := common.ParseScript(f, os.LookupEnv)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	assumeYes := r.assumeYes
+	r.assumeYes = s.AssumeYes
+	r.scriptDepth++
+	defer func() {
+		r.assumeYes = assumeYes
+		r.scriptDepth--
+	}()
+
+	fmt.Println(printPrefix, fmt.Sprintf("Running %s: %d commands", fileName, len(s.Commands)))
+	failed := 0
+	for _, c := range s.Commands {
+		fmt.Println(prefix + c.Text)
+		if r.execute(c.Text) {
+			continue
+		}
+		failed++
+		if !s.KeepGoing {
+			return fmt.Errorf("%s stopped at line %d: %s failed", fileName, c.Line, c.Text)
+		}
+		fmt.Println(printPrefix, fmt.Sprintf("%s line %d failed", fileName, c.Line))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s: %d of %d commands failed", fileName, failed, len(s.Commands))
+	}
+	fmt.Println(printPrefix, fmt.Sprintf("%s done", fileName))
+	return nil
+}
+
+// sourceScript runs the commands of a script file
+func (r *repl) sourceScript() {
+	args, ok := r.parseCommandArgs(r.params, sourceUsage, 1, nil)
+	if !ok {
+		return
+	}
+	fileName := argAt(args, 0)
+	if fileName == "" {
+		fileName = inputNotBlank(scriptFileMsg)
+	}
+	if err := r.runScript(fileName); err != nil {
+		r.fail(err)
+	}
+}
+
+// Exec runs a script file with the REPL commands, without starting the REPL.
+// It returns an error if the script can't be read or one of its commands failed.
+func Exec(c Client, s Settings, fileName string) error {
+	r := newRepl(c, s)
+	r.initializeCommands()
+	return r.runScript(fileName)
+}
@@ -6,42 +6,56 @@ import (
 	"strconv"
 
 	"github.com/spacemeshos/CLIWallet/log"
+	"github.com/spacemeshos/CLIWallet/output"
 	"github.com/spacemeshos/go-spacemesh/common/util"
 )
 
 // printSmesherRewards prints all rewards awarded to a smesher identified by an id
 func (r *repl) printSmesherRewards() {
 
-	smesherIdStr := inputNotBlank(smesherIdMsg)
+	args, ok := r.parseCommandArgs(r.params, smesherRewardsUsage, 1, nil)
+	if !ok {
+		return
+	}
+	smesherIdStr := argAt(args, 0)
+	if smesherIdStr == "" {
+		smesherIdStr = inputNotBlank(smesherIdMsg)
+	}
 	smesherId := util.FromHex(smesherIdStr)
 
 	// todo: request offset and total from user
 	rewards, total, err := r.client.SmesherRewards(smesherId, 0, 10000)
 	if err != nil {
-		log.Error("failed to list transactions: %v", err)
+		r.renderError("failed to list rewards", err)
 		return
 	}
-
-	fmt.Println(printPrefix, fmt.Sprintf("Total rewards: %d", total))
-	for _, r := range rewards {
-		printReward(r)
-		fmt.Println(printPrefix, "-----")
-	}
+	r.render(output.NewRewards(rewards, total))
 }
 
 func (r *repl) startSmeshing() {
+	args, ok := r.parseCommandArgs(r.params, startSmeshingUsage, 2, nil)
+	if !ok {
+		return
+	}
 	addr, err := r.getCurrent()
 	if err != nil {
 		log.Error("failed to get account", err)
 		return
 	}
 
-	dataDir := inputNotBlank(smeshingDatadirMsg)
+	dataDir := argAt(args, 0)
+	if dataDir == "" {
+		dataDir = inputNotBlank(smeshingDatadirMsg)
+	}
 
-	spaceGBStr := inputNotBlank(smeshingSpaceAllocationMsg)
+	spaceGBStr := argAt(args, 1)
+	if spaceGBStr == "" {
+		spaceGBStr = inputNotBlank(smeshingSpaceAllocationMsg)
+	}
 	dataSizeGB, err := strconv.ParseUint(spaceGBStr, 10, 64)
 	if err != nil {
 		log.Error("failed to parse: %v", err)
+		r.fail(startSmeshingUsage)
 		return
 	}
 
@@ -63,7 +77,8 @@ func (r *repl) startSmeshing() {
 
 func (r *repl) stopSmeshing() {
 
-	deleteData := yesOrNoQuestion(confirmDeleteDataMsg) == "y"
+	// scripts keep the smeshing data, even with --yes
+	deleteData := r.scriptDepth == 0 && r.confirm(confirmDeleteDataMsg)
 
 	resp, err := r.client.StopSmeshing(deleteData)
 
@@ -97,30 +112,26 @@ func (r *repl) printIsSmeshing() {
 	isSmeshing, err := r.client.IsSmeshing()
 
 	if err != nil {
-		log.Error("failed to get smeshing status: %v", err)
+		r.renderError("failed to get smeshing status", err)
 		return
 	}
 
-	if isSmeshing {
-		fmt.Println(printPrefix, "Smeshing is on")
-	} else {
-		fmt.Println(printPrefix, "Smeshing is off")
-	}
+	r.render(output.Smeshing{Smeshing: isSmeshing})
 }
 
 func (r *repl) printCoinbase() {
 	if resp, err := r.client.GetCoinbase(); err != nil {
-		log.Error("failed to get rewards address: %v", err)
+		r.renderError("failed to get rewards address", err)
 	} else {
-		fmt.Println(printPrefix, "Rewards address is:", resp.String())
+		r.render(output.RewardsAccount{Address: resp.String()})
 	}
 }
 
 func (r *repl) printSmesherId() {
 	if resp, err := r.client.GetSmesherId(); err != nil {
-		log.Error("failed to get smesher id: %v", err)
+		r.renderError("failed to get smesher id", err)
 	} else {
-		fmt.Println(printPrefix, "Smesher id:", "0x"+hex.EncodeToString(resp))
+		r.render(output.SmesherId{SmesherId: "0x" + hex.EncodeToString(resp)})
 	}
 }
 
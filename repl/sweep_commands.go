@@ -0,0 +1,216 @@
+package repl
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/spacemeshos/CLIWallet/log"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+)
+
+// sweep describes the transfer of the whole balance of an account
+type sweep struct {
+	account *common.LocalAccount
+	nonce   uint64
+	balance uint64
+	amount  uint64
+}
+
+// prepareSweep computes the amount which empties an account after paying fee
+func (r *repl) prepareSweep(acc *common.LocalAccount, fee uint64) (*sweep, error) {
+	r.reconcilePendingTransactions(acc.Address())
+	state, err := r.client.AccountState(acc.Address())
+	if err != nil {
+		return nil, err
+	}
+	s := &sweep{account: acc}
+	if state.StateProjected.Balance != nil {
+		s.balance = state.StateProjected.Balance.Value
+	}
+	if s.amount, err = common.SweepAmount(s.balance, fee); err != nil {
+		return nil, err
+	}
+	if s.nonce, err = r.client.NextNonce(acc.Address()); err != nil {
+		return nil, err
+	}
+	// the projected balance doesn't count the pending transactions the node doesn't know about yet
+	preflight, err := r.client.PreflightFunds(acc.Address(), s.amount, fee, s.nonce)
+	if err != nil {
+		return nil, err
+	}
+	if preflight.Insufficient {
+		return nil, errors.New(preflight.Warnings[0])
+	}
+	for _, w := range preflight.Warnings {
+		fmt.Println(printPrefix, fmt.Sprintf("Warning: %s: %s", acc.Name, w))
+	}
+	return s, nil
+}
+
+// defaultGasLimit is the gas limit of the sweeps which don't set one
+func defaultGasLimit() (uint64, error) {
+	return common.DefaultGasLimit, nil
+}
+
+func (r *repl) submitSweep(s *sweep, to gosmtypes.Address, fee, gasLimit uint64) {
+	txState, err := r.transfer(s.account, to, s.nonce, s.amount, fee, gasLimit)
+	if err != nil {
+		fmt.Println(printPrefix, fmt.Sprintf("Failed to sweep %s:", s.account.Name))
+		r.printTransferError(err)
+		return
+	}
+	fmt.Println(printPrefix, fmt.Sprintf("%s: transaction id: 0x%s state: %s", s.account.Name,
+		hex.EncodeToString(txState.Id.Id), transactionStateDisStringsMap[int32(txState.State.Number())]))
+}
+
+// sweepAccount sends the whole balance of the current account to an address
+func (r *repl) sweepAccount() {
+	var flags transactionFlags
+	args, ok := r.parseCommandArgs(r.params, sweepUsage, 1, flags.register)
+	if !ok {
+		return
+	}
+	if !r.canSubmitTransactions() {
+		r.fail("Can't submit a new transaction. Please try again later")
+		return
+	}
+	acc, err := r.getCurrent()
+	if err != nil {
+		log.Error("failed to get account", err)
+		return
+	}
+
+	var to gosmtypes.Address
+	if arg := argAt(args, 0); arg != "" {
+		if to, err = common.ParseAddress(arg); err != nil {
+			r.fail("invalid address:", err)
+			return
+		}
+	} else {
+		to = inputAddress(destAddressMsg)
+	}
+	if to == acc.Address() {
+		fmt.Println(printPrefix, "Can't sweep an account to itself")
+		return
+	}
+
+	fee, err := r.feeOrInput(flags)
+	if err != nil {
+		log.Error("invalid transaction fee", err)
+		return
+	}
+	gasLimit, err := gasLimitOr(flags, defaultGasLimit)
+	if err != nil {
+		log.Error("invalid gas limit", err)
+		return
+	}
+	s, err := r.prepareSweep(acc, fee)
+	if err != nil {
+		log.Error("failed to sweep account: %v", err)
+		return
+	}
+
+	fmt.Println(printPrefix, "Sweep summary:")
+	fmt.Println(printPrefix, "From:     ", acc.Address().String())
+	fmt.Println(printPrefix, "To:       ", to.String())
+	fmt.Println(printPrefix, "Balance:  ", coinAmount(s.balance))
+	fmt.Println(printPrefix, "Amount:   ", coinAmount(s.amount))
+	fmt.Println(printPrefix, "Fee:      ", fee, coinUnitName)
+	fmt.Println(printPrefix, "Gas limit:", gasLimit)
+	fmt.Println(printPrefix, "Nonce:    ", s.nonce)
+	fmt.Println(printPrefix, "1 Smidge stays in the account as the node requires the balance to exceed amount plus fee.")
+
+	if r.confirm(confirmTransactionMsg) {
+		r.submitSweep(s, to, fee, gasLimit)
+	}
+}
+
+// consolidateAccounts sweeps every account of the wallet into one of them
+func (r *repl) consolidateAccounts() {
+	var flags transactionFlags
+	if _, ok := r.parseCommandArgs(r.params, consolidateUsage, 0, flags.register); !ok {
+		return
+	}
+	if !r.canSubmitTransactions() {
+		r.fail("Can't submit a new transaction. Please try again later")
+		return
+	}
+	names, err := r.client.ListAccounts()
+	if err != nil {
+		log.Error("failed to list accounts: %v", err)
+		return
+	}
+	if len(names) < 2 {
+		fmt.Println(printPrefix, "The wallet has a single account, nothing to consolidate")
+		return
+	}
+
+	fmt.Println(printPrefix, "Choose the account to consolidate into:")
+	n := multipleChoice(names)
+	if n == 0 {
+		return
+	}
+	target, err := r.client.GetAccount(names[n-1])
+	if err != nil {
+		log.Error("failed to get account: %v", err)
+		return
+	}
+
+	fee, err := r.feeOrInput(flags)
+	if err != nil {
+		log.Error("invalid transaction fee", err)
+		return
+	}
+	gasLimit, err := gasLimitOr(flags, defaultGasLimit)
+	if err != nil {
+		log.Error("invalid gas limit", err)
+		return
+	}
+
+	sweeps := make([]*sweep, 0)
+	total := uint64(0)
+	fmt.Println(printPrefix, "Consolidation summary:")
+	for i, name := range names {
+		if i == n-1 {
+			continue
+		}
+		acc, err := r.client.GetAccount(name)
+		if err != nil {
+			log.Error("failed to get account: %v", err)
+			return
+		}
+		if acc.Address() == target.Address() {
+			continue
+		}
+		s, err := r.prepareSweep(acc, fee)
+		if err == common.ErrNothingToSweep {
+			fmt.Println(printPrefix, fmt.Sprintf("%-20s %s skipped: %v", name, acc.Address().String(), err))
+			continue
+		}
+		if err != nil {
+			log.Error("failed to prepare sweep of %s: %v", name, err)
+			return
+		}
+		fmt.Println(printPrefix, fmt.Sprintf("%-20s %s %s (nonce %d)", name, acc.Address().String(), coinAmount(s.amount), s.nonce))
+		sweeps = append(sweeps, s)
+		total += s.amount
+	}
+	if len(sweeps) == 0 {
+		fmt.Println(printPrefix, "Nothing to consolidate")
+		return
+	}
+	fmt.Println(printPrefix, "To:          ", target.Name, target.Address().String())
+	fmt.Println(printPrefix, "Transactions:", len(sweeps))
+	fmt.Println(printPrefix, "Total amount:", coinAmount(total))
+	fmt.Println(printPrefix, "Total fees:  ", fee*uint64(len(sweeps)), coinUnitName)
+	fmt.Println(printPrefix, "Gas limit:   ", gasLimit)
+
+	if !r.confirm(confirmTransactionMsg) {
+		return
+	}
+	for _, s := range sweeps {
+		r.submitSweep(s, target.Address(), fee, gasLimit)
+	}
+}
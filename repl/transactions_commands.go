@@ -2,12 +2,19 @@ package repl
 
 import (
 	"encoding/hex"
+	"flag"
 	"fmt"
+	"math/bits"
 	"strconv"
+	"strings"
+	"time"
 
 	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"github.com/spacemeshos/go-spacemesh/common/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/spacemeshos/CLIWallet/common"
 	"github.com/spacemeshos/CLIWallet/log"
 	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
 )
@@ -24,7 +31,10 @@ var transactionStateDisStringsMap = map[int32]string{
 
 // Print a transaction status
 func (r *repl) printTransactionStatus() {
-	txIdStr := inputNotBlank(txIdMsg)
+	txIdStr := r.params
+	if txIdStr == "" {
+		txIdStr = inputNotBlank(txIdMsg)
+	}
 	txId := util.FromHex(txIdStr)
 	txState, tx, err := r.client.TransactionState(txId, true)
 	if err != nil {
@@ -39,11 +49,64 @@ func (r *repl) printTransactionStatus() {
 		fmt.Println(printPrefix, "Unknown transaction state")
 	}
 
-	if tx != nil {
-		printTransaction(tx)
-	} else {
+	if tx == nil {
 		fmt.Println(printPrefix, "Unknown transaction")
+		return
+	}
+	printTransaction(tx, r.functions)
+
+	receipt, err := r.client.TransactionReceipt(gosmtypes.BytesToAddress(tx.Sender.GetAddress()), txId)
+	if status.Code(err) == codes.Unimplemented {
+		fmt.Println(printPrefix, "Receipt: not available from this node")
+		return
+	}
+	if err != nil {
+		log.Error("failed to get transaction receipt: %v", err)
+		return
+	}
+	if receipt == nil {
+		fmt.Println(printPrefix, "Receipt: none yet")
+		return
+	}
+	printReceipt(receipt)
+}
+
+// printAccountReceipts displays the receipts of the transactions of the current account
+func (r *repl) printAccountReceipts() {
+	acc, err := r.getCurrent()
+	if err != nil {
+		log.Error("failed to get account", err)
+		return
+	}
+
+	receipts, total, err := r.client.AccountTransactionsReceipts(acc.Address(), 0, 0)
+	if status.Code(err) == codes.Unimplemented {
+		fmt.Println(printPrefix, "Transaction receipts are not available from this node")
+		return
+	}
+	if err != nil {
+		log.Error("failed to get transaction receipts: %v", err)
+		return
 	}
+
+	fmt.Println(printPrefix, fmt.Sprintf("Total receipts: %d", total))
+	for _, receipt := range receipts {
+		fmt.Println(printPrefix, fmt.Sprintf("Transaction id: 0x%s", hex.EncodeToString(receipt.Id.GetId())))
+		printReceipt(receipt)
+		fmt.Println(printPrefix, "-----")
+	}
+}
+
+// helper method - prints a transaction receipt
+func printReceipt(receipt *apitypes.TransactionReceipt) {
+	result := common.TxResultText(receipt.Result)
+	if common.TxFailed(receipt.Result) {
+		result = strings.ToUpper(result)
+	}
+	fmt.Println(printPrefix, "Result:", result)
+	fmt.Println(printPrefix, "Layer:", receipt.Layer.GetNumber())
+	fmt.Println(printPrefix, "Gas used:", receipt.GasUsed)
+	fmt.Println(printPrefix, "Fee paid:", coinAmount(receipt.Fee.GetValue()))
 }
 
 // canSubmitTransactions returns true if the node is accepting transactions.
@@ -61,9 +124,17 @@ func (r *repl) canSubmitTransactions() bool {
 }
 
 func (r *repl) submitCoinTransaction() {
+	var feeFlag, gasLimitFlag string
+	args, ok := r.parseCommandArgs(r.params, sendCoinUsage, 2, func(fs *flag.FlagSet) {
+		fs.StringVar(&feeFlag, "fee", "", "fee")
+		fs.StringVar(&gasLimitFlag, "gas-limit", "", "gas limit")
+	})
+	if !ok {
+		return
+	}
 
 	if !r.canSubmitTransactions() {
-		fmt.Println(printPrefix, "Can't submit a new transaction. Please try again later")
+		r.fail("Can't submit a new transaction. Please try again later")
 		return
 	}
 	fmt.Println(printPrefix, initialTransferMsg)
@@ -74,41 +145,94 @@ func (r *repl) submitCoinTransaction() {
 	}
 
 	srcAddress := gosmtypes.BytesToAddress(acc.PubKey)
-	acctState, err := r.client.AccountState(srcAddress)
+	r.reconcilePendingTransactions(srcAddress)
+	nonce, err := r.client.NextNonce(srcAddress)
 	if err != nil {
-		log.Error("failed to get account info: %v", err)
+		log.Error("failed to get account nonce: %v", err)
 		return
 	}
 
-	destAddressStr := inputNotBlank(destAddressMsg)
-	destAddress := gosmtypes.HexToAddress(destAddressStr)
-
-	amountStr := inputNotBlank(amountToTransferMsg)
-
-	gas := uint64(1)
-	if yesOrNoQuestion(useDefaultGasMsg) == "n" {
-		gasStr := inputNotBlank(enterGasPrice)
-		gas, err = strconv.ParseUint(gasStr, 10, 64)
-		if err != nil {
-			log.Error("invalid transaction fee", err)
+	var destAddress gosmtypes.Address
+	if to := argAt(args, 0); to != "" {
+		if destAddress, err = common.ParseAddress(to); err != nil {
+			r.fail("invalid address:", err)
+			r.fail(sendCoinUsage)
 			return
 		}
+	} else {
+		destAddress = inputAddress(destAddressMsg)
+	}
+
+	amountStr := argAt(args, 1)
+	if amountStr == "" {
+		amountStr = inputNotBlank(amountToTransferMsg)
+	}
+	amount, err := common.ParseAmount(amountStr)
+	if err != nil {
+		log.Error("invalid amount: %v", err)
+		return
+	}
+
+	// when the recipient and amount are inline, the fee and gas limit default to the fee of the profile and the default gas limit
+	inline := len(args) == 2
+	var gas uint64
+	switch {
+	case feeFlag != "":
+		gas, err = r.feeArg(feeFlag)
+	case inline:
+		gas, err = r.feeArg(r.defaultFee())
+	default:
+		gas, err = r.inputFee()
+	}
+	if err != nil {
+		log.Error("invalid transaction fee: %v", err)
+		return
+	}
+	var gasLimit uint64
+	switch {
+	case gasLimitFlag != "":
+		gasLimit, err = strconv.ParseUint(gasLimitFlag, 10, 64)
+	case inline:
+		gasLimit = common.DefaultGasLimit
+	default:
+		gasLimit, err = r.inputGasLimit()
+	}
+	if err != nil {
+		log.Error("invalid gas limit: %v", err)
+		return
+	}
+	cost, carry := bits.Add64(amount, gas, 0)
+	if carry != 0 {
+		r.fail("Invalid transaction: the amount plus fee is too large")
+		return
 	}
 
 	fmt.Println(printPrefix, "New transaction summary:")
-	fmt.Println(printPrefix, "From:  ", srcAddress.String())
-	fmt.Println(printPrefix, "To:    ", destAddress.String())
-	fmt.Println(printPrefix, "Amount:", amountStr, coinUnitName)
-	fmt.Println(printPrefix, "Fee:   ", gas, coinUnitName)
-	fmt.Println(printPrefix, "Nonce: ", acctState.StateProjected.Counter)
+	fmt.Println(printPrefix, "From:      ", srcAddress.String())
+	fmt.Println(printPrefix, "To:        ", destAddress.String())
+	fmt.Println(printPrefix, "Amount:    ", coinAmount(amount))
+	fmt.Println(printPrefix, "Fee:       ", gas, coinUnitName)
+	fmt.Println(printPrefix, "Gas limit: ", gasLimit)
+	fmt.Println(printPrefix, "Total cost:", coinAmount(cost))
+	fmt.Println(printPrefix, "Nonce:     ", nonce)
 
-	amount, _ := strconv.ParseUint(amountStr, 10, 64)
-	// todo: handle error here!
+	preflight, err := r.client.PreflightTransfer(srcAddress, destAddress, amount, gas, nonce)
+	if err != nil {
+		log.Error("failed to check the transfer: %v", err)
+		return
+	}
+	for _, w := range preflight.Warnings {
+		fmt.Println(printPrefix, "Warning:", w)
+	}
+	if preflight.Insufficient {
+		r.fail("Transfer not sent")
+		return
+	}
 
-	if yesOrNoQuestion(confirmTransactionMsg) == "y" {
-		txState, err := r.client.Transfer(destAddress, acctState.StateProjected.Counter, amount, gas, 100, acc.PrivKey)
+	if r.confirm(confirmTransactionMsg) {
+		txState, err := r.transfer(acc, destAddress, nonce, amount, gas, gasLimit)
 		if err != nil {
-			log.Error(err.Error())
+			r.printTransferError(err)
 			return
 		}
 
@@ -117,32 +241,193 @@ func (r *repl) submitCoinTransaction() {
 		fmt.Println(printPrefix, "Transaction submitted.")
 		fmt.Println(printPrefix, fmt.Sprintf("Transaction id: 0x%v", hex.EncodeToString(txState.Id.Id)))
 		fmt.Println(printPrefix, "Transaction state:", txStateDispString)
+
+		if r.scriptDepth == 0 && r.confirm(watchTransactionMsg) {
+			r.watchTransactionId(txState.Id.Id, 1)
+		}
 	}
 }
 
-func (r *repl) printAccountTransactions() {
+// inputFee asks for the transaction fee, offering presets estimated from the fees of recent transactions
+func (r *repl) inputFee() (uint64, error) {
+	estimate, err := r.client.EstimateFees()
+	if err != nil {
+		log.Error("failed to estimate fees: %v", err)
+		e := common.EstimateFees(nil)
+		estimate = &e
+	}
+
+	if estimate.Samples > 0 {
+		fmt.Println(printPrefix, fmt.Sprintf("Choose a transaction fee (estimated from %d recent transactions):", estimate.Samples))
+	} else {
+		fmt.Println(printPrefix, "Choose a transaction fee (no recent transactions to estimate from):")
+	}
+	choices := []string{
+		fmt.Sprintf("Low: %d %s", estimate.Low, coinUnitName),
+		fmt.Sprintf("Normal: %d %s", estimate.Normal, coinUnitName),
+		fmt.Sprintf("Fast: %d %s", estimate.Fast, coinUnitName),
+		"Custom",
+	}
+	switch multipleChoice(choices) {
+	case 1:
+		return estimate.Low, nil
+	case 2:
+		return estimate.Normal, nil
+	case 3:
+		return estimate.Fast, nil
+	}
+	return strconv.ParseUint(inputNotBlank(enterGasPrice), 10, 64)
+}
+
+// inputGasLimit asks for the transaction gas limit, offering the default gas limit
+func (r *repl) inputGasLimit() (uint64, error) {
+	if r.confirm(useDefaultGasLimitMsg) {
+		return common.DefaultGasLimit, nil
+	}
+	return strconv.ParseUint(inputNotBlank(enterGasLimitMsg), 10, 64)
+}
+
+// reconcilePendingTransactions clears processed transactions from the local journal
+// and warns about transactions which were dropped by the network
+func (r *repl) reconcilePendingTransactions(address gosmtypes.Address) []common.PendingTransaction {
+	confirmed, dropped, err := r.client.ReconcilePendingTransactions(address)
+	if err != nil {
+		log.Error("failed to reconcile pending transactions: %v", err)
+		return nil
+	}
+	for _, tx := range confirmed {
+		if tx.Replaces != "" {
+			fmt.Println(printPrefix, fmt.Sprintf("Replacement transaction 0x%s was processed in place of 0x%s", tx.Id, tx.Replaces))
+		}
+	}
+	for _, tx := range dropped {
+		fmt.Println(printPrefix, fmt.Sprintf("Warning: transaction 0x%s with nonce %d was dropped by the network", tx.Id, tx.Nonce))
+	}
+	return dropped
+}
+
+func (r *repl) printPendingTransactions() {
 	acc, err := r.getCurrent()
 	if err != nil {
 		log.Error("failed to get account", err)
 		return
 	}
 
-	// todo: request offset and total from user
-	txs, total, err := r.client.GetMeshTransactions(acc.Address(), 0, 1000)
+	dropped := r.reconcilePendingTransactions(acc.Address())
+	pending, err := r.client.PendingTransactions(acc.Address())
 	if err != nil {
-		log.Error("failed to print transactions: %v", err)
+		log.Error("failed to list pending transactions: %v", err)
 		return
 	}
 
-	fmt.Println(printPrefix, fmt.Sprintf("Total mesh transactions: %d", total))
-	for _, tx := range txs {
-		printTransaction(tx)
+	fmt.Println(printPrefix, fmt.Sprintf("Pending transactions: %d", len(pending)))
+	for _, tx := range pending {
+		printPendingTransaction(tx)
 		fmt.Println(printPrefix, "-----")
 	}
+
+	if len(dropped) > 0 && r.confirm(clearDroppedMsg) {
+		if err := r.client.ClearDroppedTransactions(acc.Address()); err != nil {
+			log.Error("failed to clear dropped transactions: %v", err)
+		}
+	}
+}
+
+// helper method - prints a local journal entry
+func printPendingTransaction(tx common.PendingTransaction) {
+	fmt.Println(printPrefix, fmt.Sprintf("Transaction id: 0x%s", tx.Id))
+	fmt.Println(printPrefix, "To:", tx.Recipient)
+	fmt.Println(printPrefix, "Nonce:", tx.Nonce)
+	fmt.Println(printPrefix, "Amount:", tx.Amount, coinUnitName)
+	fmt.Println(printPrefix, "Fee:", tx.Fee, coinUnitName)
+	fmt.Println(printPrefix, "Submitted:", tx.Submitted.Local().Format(time.RFC1123))
+	if tx.Replaces != "" {
+		fmt.Println(printPrefix, fmt.Sprintf("Replaces: 0x%s", tx.Replaces))
+	}
+	if tx.Dropped {
+		fmt.Println(printPrefix, "State: Dropped")
+	}
+}
+
+func (r *repl) speedUpTransaction() {
+	r.replaceTransaction("speed-up", false)
+}
+
+func (r *repl) cancelTransaction() {
+	r.replaceTransaction("cancel", true)
+}
+
+// replaceTransaction re-signs a transaction stuck in the mempool with the same nonce and a higher fee
+func (r *repl) replaceTransaction(cmd string, cancel bool) {
+	txIdStr := r.params
+	if txIdStr == "" {
+		txIdStr = inputNotBlank(txIdMsg)
+	}
+	txId := util.FromHex(txIdStr)
+
+	txState, tx, err := r.client.TransactionState(txId, true)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	if txState == nil || tx == nil {
+		fmt.Println(printPrefix, "Unknown transaction")
+		return
+	}
+	if txState.State != apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL {
+		fmt.Println(printPrefix, "Only transactions submitted to the network and not on the mesh yet can be replaced. State:",
+			transactionStateDisStringsMap[int32(txState.State.Number())])
+		return
+	}
+
+	fmt.Println(printPrefix, "Original transaction:")
+	printTransaction(tx, r.functions)
+
+	sender := gosmtypes.BytesToAddress(tx.Sender.Address)
+	originalFee, nonce := tx.GasOffered.GetGasPrice(), tx.Counter
+	pending, err := r.client.PendingTransaction(sender, txId)
+	if err == nil && pending != nil {
+		originalFee, nonce = pending.Fee, pending.Nonce
+	} else if !cancel {
+		fmt.Println(printPrefix, "The transaction is not in the journal of this wallet, so its replacement must pass the spending policy.")
+	}
+	fmt.Println(printPrefix, "Original fee:", originalFee, coinUnitName)
+
+	fee, err := r.inputFee()
+	if err != nil {
+		log.Error("invalid transaction fee", err)
+		return
+	}
+	if fee <= originalFee {
+		fmt.Println(printPrefix, "The new fee must be higher than the original fee")
+		return
+	}
+
+	switch {
+	case cancel:
+		fmt.Println(printPrefix, "The original payment will be cancelled by sending 0", coinUnitName, "to", sender.String(), "with nonce", nonce, "and a fee of", fee, coinUnitName)
+	case pending != nil:
+		fmt.Println(printPrefix, "The original payment of", coinAmount(pending.Amount), "to", pending.Recipient, "will be sent again with nonce", nonce, "and a fee of", fee, coinUnitName)
+	default:
+		fmt.Println(printPrefix, "The original payment will be sent again with nonce", nonce, "and a fee of", fee, coinUnitName)
+	}
+	if !r.confirm(confirmTransactionMsg) {
+		return
+	}
+
+	newState, err := r.client.ReplaceTransaction(txId, fee, cancel)
+	if err != nil {
+		r.printTransferError(fmt.Errorf("failed to replace transaction: %w", err))
+		return
+	}
+	fmt.Println(printPrefix, "Replacement transaction submitted.")
+	fmt.Println(printPrefix, fmt.Sprintf("Transaction id: 0x%v", hex.EncodeToString(newState.Id.Id)))
+	fmt.Println(printPrefix, "Transaction state:", transactionStateDisStringsMap[int32(newState.State.Number())])
+	fmt.Println(printPrefix, "Use `pending` to see which of the two transactions is processed.")
 }
 
 // helper method - prints tx info
-func printTransaction(t *apitypes.Transaction) {
+func printTransaction(t *apitypes.Transaction, functions common.ContractFunctions) {
 
 	txIdStr := "0x" + util.Bytes2Hex(t.Id.Id)
 	fmt.Println(printPrefix, fmt.Sprintf("Transaction id: %v", txIdStr))
@@ -153,7 +438,8 @@ func printTransaction(t *apitypes.Transaction) {
 		fmt.Println(printPrefix, "To (coin account):", gosmtypes.BytesToAddress(ct.Receiver.Address).String())
 		fmt.Println(printPrefix, "Nonce:", t.Counter)
 		fmt.Println(printPrefix, "Amount:", t.Amount.Value, coinUnitName)
-		fmt.Println(printPrefix, "Fee:", t.GasOffered.GasProvided, coinUnitName)
+		fmt.Println(printPrefix, "Fee:", t.GasOffered.GetGasPrice(), coinUnitName)
+		fmt.Println(printPrefix, "Gas limit:", t.GasOffered.GetGasProvided())
 		return
 	}
 
@@ -163,5 +449,22 @@ func printTransaction(t *apitypes.Transaction) {
 		return
 	}
 
-	// todo: printout smart contract transaction data here
+	call := common.DecodeContractCall(sct)
+	fmt.Println(printPrefix, "Type:", call.Kind)
+	fmt.Println(printPrefix, fmt.Sprintf("%s:", call.AccountRole), call.Account.String())
+	fmt.Println(printPrefix, "Nonce:", t.Counter)
+	fmt.Println(printPrefix, "Amount:", t.Amount.GetValue(), coinUnitName)
+	fmt.Println(printPrefix, "Fee:", t.GasOffered.GetGasPrice(), coinUnitName)
+	fmt.Println(printPrefix, "Gas limit:", t.GasOffered.GetGasProvided())
+	data := call.Data
+	if function := call.Function(functions); function != "" {
+		fmt.Println(printPrefix, "Function:", function)
+		fmt.Println(printPrefix, fmt.Sprintf("Arguments (%d bytes):", len(call.Args)))
+		data = call.Args
+	} else {
+		fmt.Println(printPrefix, fmt.Sprintf("Data (%d bytes):", len(call.Data)))
+	}
+	for _, line := range common.HexLines(data, 32) {
+		fmt.Println(printPrefix, "  "+line)
+	}
 }
@@ -0,0 +1,132 @@
+package repl
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/log"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/util"
+)
+
+const (
+	// number of layers before the current layer searched for the layer including a transaction
+	txLayerLookback = 100
+	// interval between checks of the verified layer while counting confirmations
+	confirmationsPollInterval = 5 * time.Second
+)
+
+// watchTransaction follows a transaction until it is final: processed and its layer verified
+func (r *repl) watchTransaction() {
+	args := strings.Fields(r.params)
+	txIdStr := ""
+	if len(args) > 0 {
+		txIdStr = args[0]
+	} else {
+		txIdStr = inputNotBlank(txIdMsg)
+	}
+	confirmations := uint64(1)
+	if len(args) > 1 {
+		n, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil || n == 0 {
+			r.fail("usage: watch-tx <txid> [confirmations]")
+			return
+		}
+		confirmations = n
+	}
+
+	r.watchTransactionId(util.FromHex(txIdStr), confirmations)
+}
+
+func (r *repl) watchTransactionId(txId []byte, confirmations uint64) {
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	fmt.Println(printPrefix, fmt.Sprintf("Watching transaction 0x%s. Press Ctrl-C to stop", hex.EncodeToString(txId)))
+
+	finalState := apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED
+	err := r.client.WatchTransactionState(ctx, txId, func(txState *apitypes.TransactionState, tx *apitypes.Transaction) bool {
+		if txState == nil {
+			fmt.Println(printPrefix, time.Now().Format("15:04:05"), "Unknown transaction state")
+			return true
+		}
+		finalState = txState.State
+		fmt.Println(printPrefix, time.Now().Format("15:04:05"), "State:", transactionStateDisStringsMap[int32(txState.State.Number())])
+		switch txState.State {
+		case apitypes.TransactionState_TRANSACTION_STATE_PROCESSED,
+			apitypes.TransactionState_TRANSACTION_STATE_REJECTED,
+			apitypes.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS,
+			apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING:
+			return false
+		}
+		return true
+	})
+	if err == context.Canceled {
+		fmt.Println(printPrefix, "Stopped watching the transaction")
+		return
+	}
+	if err != nil {
+		log.Error("failed to watch transaction: %v", err)
+		return
+	}
+	if finalState != apitypes.TransactionState_TRANSACTION_STATE_PROCESSED {
+		r.fail("FAILED: the transaction will not be processed. Final state:",
+			transactionStateDisStringsMap[int32(finalState.Number())])
+		return
+	}
+
+	layer, err := r.transactionLayer(txId)
+	if err != nil {
+		log.Error("failed to find the layer of the transaction: %v", err)
+		return
+	}
+	fmt.Println(printPrefix, "Included in layer:", layer)
+
+	seen := uint64(0)
+	for {
+		status, err := r.client.NodeStatus()
+		if err != nil {
+			log.Error("failed to get node status: %v", err)
+			return
+		}
+		verified := uint64(status.VerifiedLayer.Number)
+		if verified >= uint64(layer) && verified-uint64(layer)+1 > seen {
+			seen = verified - uint64(layer) + 1
+			fmt.Println(printPrefix, time.Now().Format("15:04:05"), "Confirmations:", seen, "verified layer:", verified)
+		}
+		if seen >= confirmations {
+			fmt.Println(printPrefix, "SUCCESS: the transaction is final")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Println(printPrefix, "Stopped watching the transaction")
+			return
+		case <-time.After(confirmationsPollInterval):
+		}
+	}
+}
+
+// transactionLayer returns the number of the layer which includes a transaction
+func (r *repl) transactionLayer(txId []byte) (uint32, error) {
+	info, err := r.client.GetMeshInfo()
+	if err != nil {
+		return 0, err
+	}
+	start := uint32(0)
+	if info.CurrentLayer > txLayerLookback {
+		start = info.CurrentLayer - txLayerLookback
+	}
+	layer, found, err := r.client.FindTransactionLayer(txId, start, info.CurrentLayer)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("transaction not found in the last %d layers", txLayerLookback)
+	}
+	return layer, nil
+}
@@ -0,0 +1,50 @@
+package smWallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicies(t *testing.T) {
+	w, err := NewWallet("policies", "password")
+	require.NoError(t, err)
+	addr, err := w.GetAddress(0)
+	require.NoError(t, err)
+
+	p, err := w.GetPolicy(addr.String())
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	require.NoError(t, w.SetPolicy(common.SpendingPolicy{Account: addr.String(), TxLimit: 10}))
+	require.NoError(t, w.SetPolicy(common.SpendingPolicy{Account: addr.String(), TxLimit: 20}))
+	p, err = w.GetPolicy(addr.String())
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, uint64(20), p.TxLimit)
+
+	// policies survive encryption
+	w2 := &Wallet{password: "password", Meta: w.Meta, Crypto: walletEncryptedData{Cipher: w.Crypto.Cipher, CipherText: w.Crypto.CipherText}}
+	require.NoError(t, w2.Unlock("password"))
+	p, err = w2.GetPolicy(addr.String())
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, uint64(20), p.TxLimit)
+
+	// spend records are encrypted with the policy
+	now := time.Now()
+	require.NoError(t, w.AddSpend(addr.String(), common.SpendRecord{Time: now, Amount: 7}))
+	w2 = &Wallet{password: "password", Meta: w.Meta, Crypto: walletEncryptedData{Cipher: w.Crypto.Cipher, CipherText: w.Crypto.CipherText}}
+	require.NoError(t, w2.Unlock("password"))
+	spent, err := w2.SpentSince(addr.String(), now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), spent)
+
+	require.NoError(t, w.RemovePolicy(addr.String()))
+	p, err = w.GetPolicy(addr.String())
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
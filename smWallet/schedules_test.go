@@ -0,0 +1,74 @@
+package smWallet
+
+import (
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/spacemeshos/CLIWallet/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedules(t *testing.T) {
+	w, err := NewWallet("schedules", "password")
+	require.NoError(t, err)
+	addr, err := w.GetAddress(0)
+	require.NoError(t, err)
+
+	s := common.ScheduledPayment{Id: "1", Account: addr.String(), Recipient: addr.String(), Amount: 10, Kind: common.ScheduleEveryEpoch}
+	require.NoError(t, w.AddSchedule(s))
+	assert.Error(t, w.AddSchedule(common.ScheduledPayment{Id: "2"}))
+
+	s.LastLayer = 50
+	require.NoError(t, w.UpdateSchedule(s))
+	schedules, err := w.GetSchedules()
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, uint32(50), schedules[0].LastLayer)
+
+	// schedules survive encryption
+	w2 := &Wallet{password: "password", Meta: w.Meta, Crypto: walletEncryptedData{Cipher: w.Crypto.Cipher, CipherText: w.Crypto.CipherText}}
+	require.NoError(t, w2.Unlock("password"))
+	schedules, err = w2.GetSchedules()
+	require.NoError(t, err)
+	assert.Len(t, schedules, 1)
+
+	require.NoError(t, w.RemoveSchedule("1"))
+	assert.Error(t, w.RemoveSchedule("1"))
+	schedules, err = w.GetSchedules()
+	require.NoError(t, err)
+	assert.Empty(t, schedules)
+}
+
+func TestConcurrentSchedules(t *testing.T) {
+	w, err := NewWallet("schedules", "password")
+	require.NoError(t, err)
+	w.keystore = filepath.Join(t.TempDir(), "wallet.json")
+	addr, err := w.GetAddress(0)
+	require.NoError(t, err)
+
+	// the scheduler updates the wallet while the REPL commands change it
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := common.ScheduledPayment{Id: strconv.Itoa(i), Account: addr.String(), Recipient: addr.String(), Amount: 10, Kind: common.ScheduleEveryEpoch}
+			assert.NoError(t, w.AddSchedule(s))
+			s.LastLayer = 50
+			assert.NoError(t, w.UpdateSchedule(s))
+			_, err := w.GetSchedules()
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	loaded, err := LoadWallet(w.WalletPath())
+	require.NoError(t, err)
+	require.NoError(t, loaded.Unlock("password"))
+	schedules, err := loaded.GetSchedules()
+	require.NoError(t, err)
+	assert.Len(t, schedules, 2)
+}
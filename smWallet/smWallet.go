@@ -6,9 +6,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sync"
 
 	xdr "github.com/davecgh/go-xdr/xdr2"
+	"github.com/spacemeshos/CLIWallet/common"
 	"github.com/spacemeshos/ed25519"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/common/util"
@@ -25,6 +28,8 @@ const (
 	ErrorWalletDoesNotHaveThatAddress = "You are attempting to access an account that has not been generated."
 	// ErrorWalletDoesNotHavePassword This wallet does not have a password.
 	ErrorWalletDoesNotHavePassword = "Invalid State. This wallet does not have a password."
+	// ErrorWalletDoesNotHaveThatSchedule if attempting to access a scheduled payment that does not exist
+	ErrorWalletDoesNotHaveThatSchedule = "You are attempting to access a scheduled payment that does not exist."
 )
 
 type account struct {
@@ -44,16 +49,28 @@ func (a *account) PrivateKey() (pub ed25519.PrivateKey, err error) {
 }
 
 func (w *Wallet) CurrentAccount() (*account, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.currentAccount()
+}
+
+// currentAccount returns a copy of the current account. w.mu must be held.
+func (w *Wallet) currentAccount() (*account, error) {
 	if !w.unlocked {
 		return nil, errors.New(ErrorWalletNotUnlocked)
 	}
-	return &w.Crypto.confidential.Accounts[w.Crypto.confidential.accountNumber], nil
+	acc := w.Crypto.confidential.Accounts[w.Crypto.confidential.accountNumber]
+	return &acc, nil
 }
 
 type secretStuff struct {
-	Mnemonic      string    `json:"mnemonic"`
-	Accounts      []account `json:"accounts"`
-	Contacts      []contact `json:"contacts"`
+	Mnemonic  string                    `json:"mnemonic"`
+	Accounts  []account                 `json:"accounts"`
+	Contacts  []contact                 `json:"contacts"`
+	Schedules []common.ScheduledPayment `json:"schedules,omitempty"`
+	Policies  []common.SpendingPolicy   `json:"policies,omitempty"`
+	// amounts recently sent by the accounts with a policy, by account
+	Spent         map[string][]common.SpendRecord `json:"spent,omitempty"`
 	accountNumber int
 }
 
@@ -77,8 +94,10 @@ type walletEncryptedData struct {
 	confidential secretStuff
 }
 
-// Wallet is the basic data structure.
+// Wallet is the basic data structure. Its methods are safe for concurrent use, such as by the
+// scheduled payments running in the background.
 type Wallet struct {
+	mu       sync.Mutex // guards the wallet and its file
 	keystore string
 	password string
 	unlocked bool
@@ -133,24 +152,33 @@ func LoadWallet(keystore string) (w *Wallet, err error) {
 
 // SaveWalletAs saves a wallet to a file and records the filename internally
 func (w *Wallet) SaveWalletAs(keystorePrefix string) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.keystore = keystorePrefix + "_" + w.Meta.Created + ".json"
-	return w.SaveWallet()
+	return w.saveWallet()
 }
 
 // SaveWallet saves a file only if it already has a filename
 func (w *Wallet) SaveWallet() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.saveWallet()
+}
+
+// saveWallet saves the wallet to its file. w.mu must be held.
+func (w *Wallet) saveWallet() (err error) {
 	if len(w.keystore) == 0 {
 		return errors.New(ErrorNoFileName)
 	}
-	f, err := os.Create(w.keystore)
-	if err != nil {
-		return err
-	}
-	return json.NewEncoder(f).Encode(w)
+	return common.WriteFileAtomic(w.keystore, 0600, func(f io.Writer) error {
+		return json.NewEncoder(f).Encode(w)
+	})
 }
 
 // Unlock a previously unlocked wallet
 func (w *Wallet) Unlock(password string) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if w.unlocked {
 		return nil
 	}
@@ -181,10 +209,12 @@ func interfaceToBytes(i interface{}) ([]byte, error) {
 
 // SignedTransaction turns a transaction into a signed transaction :-)
 func (w *Wallet) SignedTransaction(t *types.Transaction) ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if !w.unlocked {
 		return []byte{}, errors.New(ErrorWalletNotUnlocked)
 	}
-	acc, _ := w.CurrentAccount()
+	acc, _ := w.currentAccount()
 	key, _ := acc.PrivateKey()
 
 	tx := struct {
@@ -207,5 +237,7 @@ func (w *Wallet) SignedTransaction(t *types.Transaction) ([]byte, error) {
 }
 
 func (w *Wallet) WalletPath() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.keystore
 }
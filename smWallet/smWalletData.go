@@ -8,7 +8,9 @@ import (
 )
 
 // GetMnemonic returns the mnemonic string associated with the wallet
-func (w Wallet) GetMnemonic() (string, error) {
+func (w *Wallet) GetMnemonic() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if !w.unlocked {
 		return "", errors.New(ErrorWalletNotUnlocked)
 	}
@@ -16,7 +18,9 @@ func (w Wallet) GetMnemonic() (string, error) {
 }
 
 // GetNumberOfAccounts returns the number of accounts held in said wallet
-func (w Wallet) GetNumberOfAccounts() (int, error) {
+func (w *Wallet) GetNumberOfAccounts() (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if !w.unlocked {
 		return 0, errors.New(ErrorWalletNotUnlocked)
 	}
@@ -25,6 +29,8 @@ func (w Wallet) GetNumberOfAccounts() (int, error) {
 
 // GetAddress retrieves an address from a wallet if unlocked and it has been generated
 func (w *Wallet) GetAddress(accountNumber int) (types.Address, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if !w.unlocked {
 		return types.Address{}, errors.New(ErrorWalletNotUnlocked)
 	}
@@ -45,6 +51,8 @@ func (w *Wallet) GetPublicKey(accountNumber int) (ed25519.PublicKey, error) {
 
 // GetPrivateKey retrieve the private key
 func (w *Wallet) GetPrivateKey(accountNumber int) (ed25519.PrivateKey, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if !w.unlocked {
 		return []byte{}, errors.New(ErrorWalletNotUnlocked)
 	}
@@ -61,6 +69,8 @@ func (w *Wallet) GetPrivateKey(accountNumber int) (ed25519.PrivateKey, error) {
 
 // GetAccountDisplayName retrieves an account name from a wallet (if unlocked and account exists)
 func (w *Wallet) GetAccountDisplayName(accountNumber int) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if !w.unlocked {
 		return "", errors.New(ErrorWalletNotUnlocked)
 	}
@@ -72,6 +82,8 @@ func (w *Wallet) GetAccountDisplayName(accountNumber int) (string, error) {
 
 // SetCurrent - set current wallet by number
 func (w *Wallet) SetCurrent(accountNumber int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if !w.unlocked {
 		return errors.New(ErrorWalletNotUnlocked)
 	}
@@ -83,6 +95,8 @@ func (w *Wallet) SetCurrent(accountNumber int) error {
 }
 
 func (w *Wallet) AddContact(nickname string, address types.Address) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if !w.unlocked {
 		return errors.New(ErrorWalletNotUnlocked)
 	}
@@ -91,3 +105,17 @@ func (w *Wallet) AddContact(nickname string, address types.Address) error {
 	err := w.reCrypt()
 	return err
 }
+
+// GetContacts returns the address book of the wallet as a map from address to nickname
+func (w *Wallet) GetContacts() (map[string]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return nil, errors.New(ErrorWalletNotUnlocked)
+	}
+	contacts := make(map[string]string, len(w.Crypto.confidential.Contacts))
+	for _, c := range w.Crypto.confidential.Contacts {
+		contacts[c.Address] = c.Nickname
+	}
+	return contacts, nil
+}
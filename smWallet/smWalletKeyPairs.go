@@ -58,6 +58,8 @@ func (w *Wallet) newAccount(displayName string) (*account, error) {
 
 // GenerateNewPair - add a new pair based on mnemonic key phrase
 func (w *Wallet) GenerateNewPair(displayName string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	ac, err := w.newAccount(displayName)
 	if err != nil {
 		return 0, err
@@ -0,0 +1,82 @@
+package smWallet
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spacemeshos/CLIWallet/common"
+)
+
+// GetPolicy returns the spending policy of an account, or nil if the account has none
+func (w *Wallet) GetPolicy(account string) (*common.SpendingPolicy, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return nil, errors.New(ErrorWalletNotUnlocked)
+	}
+	for _, p := range w.Crypto.confidential.Policies {
+		if p.Account == account {
+			policy := p
+			return &policy, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetPolicy stores the spending policy of an account, replacing its current policy
+func (w *Wallet) SetPolicy(p common.SpendingPolicy) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return errors.New(ErrorWalletNotUnlocked)
+	}
+	for i := range w.Crypto.confidential.Policies {
+		if w.Crypto.confidential.Policies[i].Account == p.Account {
+			w.Crypto.confidential.Policies[i] = p
+			return w.reCrypt()
+		}
+	}
+	w.Crypto.confidential.Policies = append(w.Crypto.confidential.Policies, p)
+	return w.reCrypt()
+}
+
+// RemovePolicy deletes the spending policy of an account
+func (w *Wallet) RemovePolicy(account string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return errors.New(ErrorWalletNotUnlocked)
+	}
+	policies := w.Crypto.confidential.Policies
+	for i := range policies {
+		if policies[i].Account == account {
+			w.Crypto.confidential.Policies = append(policies[:i:i], policies[i+1:]...)
+			return w.reCrypt()
+		}
+	}
+	return nil
+}
+
+// AddSpend records an amount sent by an account for the daily limit of its policy
+func (w *Wallet) AddSpend(account string, r common.SpendRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return errors.New(ErrorWalletNotUnlocked)
+	}
+	if w.Crypto.confidential.Spent == nil {
+		w.Crypto.confidential.Spent = make(map[string][]common.SpendRecord)
+	}
+	w.Crypto.confidential.Spent[account] = common.AddSpendRecord(w.Crypto.confidential.Spent[account], r)
+	return w.reCrypt()
+}
+
+// SpentSince returns the total amount recorded as sent by an account since t
+func (w *Wallet) SpentSince(account string, t time.Time) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return 0, errors.New(ErrorWalletNotUnlocked)
+	}
+	return common.SpentSince(w.Crypto.confidential.Spent[account], t), nil
+}
@@ -0,0 +1,64 @@
+package smWallet
+
+import (
+	"errors"
+
+	"github.com/spacemeshos/CLIWallet/common"
+)
+
+// GetSchedules returns the scheduled payments held in the wallet
+func (w *Wallet) GetSchedules() ([]common.ScheduledPayment, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return nil, errors.New(ErrorWalletNotUnlocked)
+	}
+	return append([]common.ScheduledPayment(nil), w.Crypto.confidential.Schedules...), nil
+}
+
+// AddSchedule adds a scheduled payment to the wallet
+func (w *Wallet) AddSchedule(s common.ScheduledPayment) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return errors.New(ErrorWalletNotUnlocked)
+	}
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	w.Crypto.confidential.Schedules = append(w.Crypto.confidential.Schedules, s)
+	return w.reCrypt()
+}
+
+// UpdateSchedule replaces the scheduled payment with the same id
+func (w *Wallet) UpdateSchedule(s common.ScheduledPayment) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return errors.New(ErrorWalletNotUnlocked)
+	}
+	for i := range w.Crypto.confidential.Schedules {
+		if w.Crypto.confidential.Schedules[i].Id == s.Id {
+			w.Crypto.confidential.Schedules[i] = s
+			return w.reCrypt()
+		}
+	}
+	return errors.New(ErrorWalletDoesNotHaveThatSchedule)
+}
+
+// RemoveSchedule deletes a scheduled payment from the wallet
+func (w *Wallet) RemoveSchedule(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return errors.New(ErrorWalletNotUnlocked)
+	}
+	schedules := w.Crypto.confidential.Schedules
+	for i := range schedules {
+		if schedules[i].Id == id {
+			w.Crypto.confidential.Schedules = append(schedules[:i:i], schedules[i+1:]...)
+			return w.reCrypt()
+		}
+	}
+	return errors.New(ErrorWalletDoesNotHaveThatSchedule)
+}
@@ -31,6 +31,7 @@ func (w *Wallet) twoWayAES(in []byte) ([]byte, error) {
 	return crypt(c, in, iv), nil
 }
 
+// reCrypt encrypts the confidential data and saves the wallet. w.mu must be held.
 func (w *Wallet) reCrypt() error {
 	if len(w.password) == 0 {
 		return errors.New("ErrorWalletDoesNotHavePassword")
@@ -45,7 +46,7 @@ func (w *Wallet) reCrypt() error {
 	}
 	w.Crypto.CipherText = util.Bytes2Hex(ciphertext)
 	if len(w.keystore) > 0 {
-		return w.SaveWallet()
+		return w.saveWallet()
 	}
 	return nil
 }